@@ -0,0 +1,242 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ApprovalRequest 等待人工批准的工具调用
+type ApprovalRequest struct {
+	ID         string                 `json:"id"`
+	Agent      string                 `json:"agent"`
+	Tool       string                 `json:"tool"`
+	ToolCallID string                 `json:"tool_call_id,omitempty"`
+	Arguments  map[string]interface{} `json:"arguments"`
+	SessionID  string                 `json:"session_id,omitempty"`
+	CreatedAt  time.Time              `json:"created_at"`
+}
+
+// ApprovalDecision 审批结果
+type ApprovalDecision struct {
+	Approved bool   `json:"approved"`
+	Reason   string `json:"reason,omitempty"` // 拒绝原因，会返回给模型
+}
+
+// Approver 审批工具调用，返回错误时视为拒绝
+type Approver interface {
+	Approve(ctx context.Context, req ApprovalRequest) (ApprovalDecision, error)
+}
+
+// ToolPolicy 工具调用的审批策略
+type ToolPolicy struct {
+	Approver        Approver      // 审批者
+	RequireApproval []string      // 需要批准才能执行的工具名称，"*" 表示所有工具
+	Timeout         time.Duration // 等待批准的最长时间，超时视为拒绝，0 表示一直等待
+}
+
+// requires 判断工具是否需要批准
+func (p ToolPolicy) requires(name string) bool {
+	for _, n := range p.RequireApproval {
+		if n == "*" || n == name {
+			return true
+		}
+	}
+	return false
+}
+
+var approvalSeq uint64
+
+// approve 按策略请求批准，拒绝时返回 ToolErrorDenied 类型的错误
+func (p ToolPolicy) approve(ctx context.Context, agentName, toolCallID, tool string, params map[string]interface{}) error {
+	if !p.requires(tool) {
+		return nil
+	}
+	denied := func(reason string) error {
+		return &toolCallError{Kind: ToolErrorDenied, Tool: tool, Err: fmt.Errorf("调用未获批准: %s", reason)}
+	}
+	if p.Approver == nil {
+		return denied("没有可用的审批者")
+	}
+	if p.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.Timeout)
+		defer cancel()
+	}
+	req := ApprovalRequest{
+		ID:         fmt.Sprintf("approval_%d_%d", time.Now().UnixNano(), atomic.AddUint64(&approvalSeq, 1)),
+		Agent:      agentName,
+		Tool:       tool,
+		ToolCallID: toolCallID,
+		Arguments:  params,
+		CreatedAt:  time.Now(),
+	}
+	if s := SessionFromContext(ctx); s != nil {
+		req.SessionID = s.ID()
+	}
+	decision, err := p.Approver.Approve(ctx, req)
+	switch {
+	case err != nil:
+		return denied(err.Error())
+	case !decision.Approved && decision.Reason != "":
+		return denied(decision.Reason)
+	case !decision.Approved:
+		return denied("审批者拒绝")
+	}
+	return nil
+}
+
+// TerminalApprover 在终端中询问是否批准，同一时间只询问一个调用
+type TerminalApprover struct {
+	in  *lineReader
+	out io.Writer
+}
+
+// NewTerminalApprover 创建终端审批者，从in逐行读取回答，提示写入out
+//
+// 回答 y 或 yes 表示批准，其他内容作为拒绝原因返回给模型，空行或 n 表示直接拒绝。
+// 只在询问时读取in，因此可以与其他读取同一终端的组件（如交互式对话）共用。
+func NewTerminalApprover(in io.Reader, out io.Writer) *TerminalApprover {
+	return &TerminalApprover{in: newLineReader(in), out: out}
+}
+
+func (t *TerminalApprover) Approve(ctx context.Context, req ApprovalRequest) (ApprovalDecision, error) {
+	t.in.mu.Lock()
+	defer t.in.mu.Unlock()
+	args, _ := json.Marshal(req.Arguments)
+	fmt.Fprintf(t.out, "\n[审批] %s 请求调用工具 %s，参数: %s\n批准执行？[y/N，或输入拒绝原因] ", req.Agent, req.Tool, args)
+
+	line, err := t.in.readLine(ctx)
+	if err != nil {
+		if ctx.Err() != nil {
+			fmt.Fprintln(t.out)
+			return ApprovalDecision{}, fmt.Errorf("等待批准超时或被取消: %w", err)
+		}
+		return ApprovalDecision{}, fmt.Errorf("无法读取审批输入: %w", err)
+	}
+	answer := strings.TrimSpace(line)
+	switch strings.ToLower(answer) {
+	case "y", "yes":
+		return ApprovalDecision{Approved: true}, nil
+	case "", "n", "no":
+		return ApprovalDecision{Reason: "用户拒绝"}, nil
+	default:
+		return ApprovalDecision{Reason: answer}, nil
+	}
+}
+
+// PendingApproval 等待回复的审批请求
+type PendingApproval struct {
+	ApprovalRequest
+	decision chan ApprovalDecision
+	once     sync.Once
+}
+
+// Approve 批准执行
+func (p *PendingApproval) Approve() {
+	p.Decide(ApprovalDecision{Approved: true})
+}
+
+// Deny 拒绝执行，reason 会返回给模型
+func (p *PendingApproval) Deny(reason string) {
+	p.Decide(ApprovalDecision{Reason: reason})
+}
+
+// Decide 回复审批结果，只有第一次回复有效，返回本次回复是否被接受
+func (p *PendingApproval) Decide(decision ApprovalDecision) bool {
+	delivered := false
+	p.once.Do(func() {
+		p.decision <- decision
+		delivered = true
+	})
+	return delivered
+}
+
+// ChannelApprover 通过通道把审批请求交给其他组件（如服务端接口）处理
+type ChannelApprover struct {
+	requests chan *PendingApproval
+	pending  map[string]*PendingApproval
+	notify   func(ApprovalRequest)
+	mu       sync.Mutex
+}
+
+// NewChannelApprover 创建基于通道的审批者，buffer 为请求通道的缓冲大小
+func NewChannelApprover(buffer int) *ChannelApprover {
+	return &ChannelApprover{
+		requests: make(chan *PendingApproval, buffer),
+		pending:  make(map[string]*PendingApproval),
+	}
+}
+
+// Requests 返回审批请求通道，处理方对每个请求调用 Approve、Deny 或 Decide
+func (c *ChannelApprover) Requests() <-chan *PendingApproval {
+	return c.requests
+}
+
+// SetNotify 设置新请求的通知函数，调用时请求已可以通过 Decide 按ID回复
+func (c *ChannelApprover) SetNotify(notify func(ApprovalRequest)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.notify = notify
+}
+
+// Pending 返回尚未回复的审批请求，sessionID 不为空时只返回该会话的请求，按创建时间排序
+func (c *ChannelApprover) Pending(sessionID string) []ApprovalRequest {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	list := make([]ApprovalRequest, 0, len(c.pending))
+	for _, p := range c.pending {
+		if sessionID == "" || p.SessionID == sessionID {
+			list = append(list, p.ApprovalRequest)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].CreatedAt.Before(list[j].CreatedAt)
+	})
+	return list
+}
+
+// Decide 按ID回复审批请求，请求不存在或已回复时返回false
+func (c *ChannelApprover) Decide(id string, decision ApprovalDecision) bool {
+	c.mu.Lock()
+	p, ok := c.pending[id]
+	c.mu.Unlock()
+	return ok && p.Decide(decision)
+}
+
+func (c *ChannelApprover) Approve(ctx context.Context, req ApprovalRequest) (ApprovalDecision, error) {
+	p := &PendingApproval{ApprovalRequest: req, decision: make(chan ApprovalDecision, 1)}
+	c.mu.Lock()
+	c.pending[req.ID] = p
+	notify := c.notify
+	c.mu.Unlock()
+	if notify != nil {
+		notify(req)
+	}
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, req.ID)
+		c.mu.Unlock()
+	}()
+
+	select {
+	case c.requests <- p:
+	case decision := <-p.decision:
+		// 处理方通过 Decide 按ID直接回复，未从通道取走请求
+		return decision, nil
+	case <-ctx.Done():
+		return ApprovalDecision{}, fmt.Errorf("等待批准超时或被取消: %w", ctx.Err())
+	}
+	select {
+	case decision := <-p.decision:
+		return decision, nil
+	case <-ctx.Done():
+		return ApprovalDecision{}, fmt.Errorf("等待批准超时或被取消: %w", ctx.Err())
+	}
+}
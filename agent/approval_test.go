@@ -0,0 +1,104 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestDeniedToolCall(t *testing.T) {
+	approver := NewChannelApprover(0)
+	done := make(chan []*PendingApproval, 1)
+	go func() {
+		// 拒绝前两次调用，批准第三次
+		var handled []*PendingApproval
+		for p := range approver.Requests() {
+			if len(handled) < 2 {
+				p.Deny("不允许访问生产环境")
+			} else {
+				p.Approve()
+			}
+			if handled = append(handled, p); len(handled) == 3 {
+				break
+			}
+		}
+		done <- handled
+	}()
+
+	llm := &scriptedLLM{}
+	llm.replies = append(llm.replies,
+		callTools(toolCall("call_1", "echo", `{"x": "a"}`)),
+		callTools(toolCall("call_2", "echo", `{"x": "b"}`)),
+		callTools(toolCall("call_3", "echo", `{"x": "c"}`)),
+	)
+	a := newToolAgent(t, llm, echoTool("echo"))
+	a.SetToolPolicy(ToolPolicy{Approver: approver, RequireApproval: []string{"echo"}})
+	// 只允许一次失败，拒绝不计入连续失败次数
+	a.SetMaxToolFailures(1)
+
+	session := NewSession("approval")
+	output, err := a.Execute(WithSession(context.Background(), session), "开始")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(output, "done") {
+		t.Errorf("output %q does not end with the final answer", output)
+	}
+
+	handled := <-done
+	for i, p := range handled {
+		if p.Tool != "echo" || p.Agent != "worker" || p.SessionID != "approval" {
+			t.Errorf("request %d = %+v", i, p.ApprovalRequest)
+		}
+		// 已回复的请求不能再次回复
+		if p.Decide(ApprovalDecision{Approved: true}) {
+			t.Errorf("request %d accepted a second decision", i)
+		}
+	}
+
+	// 被拒绝的调用以 tool 消息返回拒绝原因，模型可以据此调整
+	results := toolMessages(llm.lastRequest())
+	if len(results) != 3 {
+		t.Fatalf("got %d tool results, want 3", len(results))
+	}
+	for _, r := range results[:2] {
+		if toolErrorType(r.Content) != ToolErrorDenied || !strings.Contains(r.Content, "不允许访问生产环境") {
+			t.Errorf("denied result = %+v", r)
+		}
+	}
+	if results[2].ToolCallID != "call_3" || results[2].Content != "c" {
+		t.Errorf("approved result = %+v", results[2])
+	}
+}
+
+func TestChannelApproverDecideByID(t *testing.T) {
+	approver := NewChannelApprover(0)
+	notified := make(chan ApprovalRequest, 1)
+	approver.SetNotify(func(req ApprovalRequest) { notified <- req })
+
+	result := make(chan ApprovalDecision, 1)
+	go func() {
+		decision, err := approver.Approve(context.Background(), ApprovalRequest{ID: "approval_1", Tool: "echo", SessionID: "s"})
+		if err != nil {
+			t.Error(err)
+		}
+		result <- decision
+	}()
+
+	req := <-notified
+	if pending := approver.Pending("s"); len(pending) != 1 || pending[0].ID != req.ID {
+		t.Fatalf("pending = %+v", pending)
+	}
+	if pending := approver.Pending("other"); len(pending) != 0 {
+		t.Errorf("pending of another session = %+v", pending)
+	}
+	if !approver.Decide(req.ID, ApprovalDecision{Reason: "拒绝"}) {
+		t.Fatal("Decide returned false for a pending request")
+	}
+	if decision := <-result; decision.Approved || decision.Reason != "拒绝" {
+		t.Errorf("decision = %+v", decision)
+	}
+	if approver.Decide(req.ID, ApprovalDecision{Approved: true}) {
+		t.Error("Decide returned true for a decided request")
+	}
+}
@@ -1,6 +1,7 @@
 package agent
 
 import (
+	"context"
 	"multi-agent/oneapi"
 )
 
@@ -9,20 +10,25 @@ type BaseAgent struct {
 	name         string
 	capabilities []string
 	client       *oneapi.Client
-	memory       *Memory
+	memory       *Memory // 不在会话中执行时使用的记忆
 	memoryMgr    *MemoryManager
 	taskID       string
 }
 
-// NewBaseAgent 创建新的基础Agent
+// NewBaseAgent 创建新的基础Agent。memoryMgr 为nil时Agent使用自己的记忆，
+// 否则不在会话中执行时使用 memoryMgr 中 taskID 对应的记忆（已废弃，见 MemoryManager）
 func NewBaseAgent(name string, capabilities []string, client *oneapi.Client, memoryMgr *MemoryManager, taskID string) *BaseAgent {
+	memory := NewMemory()
+	if memoryMgr != nil {
+		memory = memoryMgr.GetMemory(taskID)
+	}
 	return &BaseAgent{
 		name:         name,
 		capabilities: capabilities,
 		client:       client,
+		memory:       memory,
 		memoryMgr:    memoryMgr,
 		taskID:       taskID,
-		memory:       memoryMgr.GetMemory(taskID),
 	}
 }
 
@@ -34,9 +40,47 @@ func (b *BaseAgent) GetCapabilities() []string {
 	return b.capabilities
 }
 
+// ResetMemory 清空Agent在会话之外的对话记忆
+func (b *BaseAgent) ResetMemory() {
+	b.memory.Clear()
+}
+
 // ClearTaskMemory 清理当前任务的Memory
+//
+// Deprecated: 使用 ResetMemory，或为每次执行传入新的 Session。
 func (b *BaseAgent) ClearTaskMemory() {
+	b.memory.Clear()
 	if b.memoryMgr != nil {
 		b.memoryMgr.ClearTaskMemory(b.taskID)
 	}
 }
+
+// memoryFor 返回本次执行使用的记忆：优先使用上下文中会话的私有记忆，否则使用Agent自身的记忆
+func (b *BaseAgent) memoryFor(ctx context.Context) *Memory {
+	if s := SessionFromContext(ctx); s != nil {
+		return s.Memory(b.name)
+	}
+	return b.memory
+}
+
+// syncShared 将其他Agent的新发言加入私有记忆并返回这些发言。
+// 会话按Agent记录已同步到的共享发言位置，每条发言只会加入一次
+func (b *BaseAgent) syncShared(ctx context.Context, memory *Memory) []oneapi.ChatMessage {
+	s := SessionFromContext(ctx)
+	if s == nil {
+		return nil
+	}
+	added := s.unseen(b.name)
+	for _, msg := range added {
+		memory.AddMessage(msg)
+		s.Recorder().message(b.name, msg)
+	}
+	return added
+}
+
+// postShared 将最终回复以自己的名义写入共享发言记录
+func (b *BaseAgent) postShared(ctx context.Context, output string) {
+	if s := SessionFromContext(ctx); s != nil && output != "" {
+		s.Post(b.name, output)
+	}
+}
@@ -0,0 +1,242 @@
+package agent
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AnyVersion 写入黑板时不检查版本
+const AnyVersion = -1
+
+// BlackboardEntry 黑板上的一个键值
+type BlackboardEntry struct {
+	Key       string      `json:"key"`
+	Value     interface{} `json:"value"`
+	Type      string      `json:"type"`             // 值的类型：string、number、boolean、object、array
+	Version   int         `json:"version"`          // 每次写入递增，从1开始
+	Author    string      `json:"author,omitempty"` // 最后写入者
+	UpdatedAt time.Time   `json:"updated_at"`
+}
+
+// BlackboardEvent 键的变更事件
+type BlackboardEvent struct {
+	Key      string           `json:"key"`
+	Entry    *BlackboardEntry `json:"entry,omitempty"` // 变更后的值，删除时为nil
+	Previous *BlackboardEntry `json:"previous,omitempty"`
+	Deleted  bool             `json:"deleted,omitempty"`
+	Author   string           `json:"author,omitempty"`
+}
+
+// blackboardWatcher 变更订阅
+type blackboardWatcher struct {
+	prefix string
+	fn     func(BlackboardEvent)
+}
+
+// Blackboard 一次运行中各Agent共享的结构化键值工作区
+//
+// 每个键的值带有类型和版本：键的类型确定后不能改为其他类型（需先删除），
+// 写入时可以指定期望的版本，实现比较并交换，例如多个Agent争抢同一任务时只有一个能成功认领。
+type Blackboard struct {
+	entries  map[string]*BlackboardEntry
+	watchers map[int]blackboardWatcher
+	nextID   int
+	mu       sync.RWMutex
+}
+
+// NewBlackboard 创建黑板
+func NewBlackboard() *Blackboard {
+	return &Blackboard{
+		entries:  make(map[string]*BlackboardEntry),
+		watchers: make(map[int]blackboardWatcher),
+	}
+}
+
+// Get 读取键，不存在时返回false
+func (b *Blackboard) Get(key string) (BlackboardEntry, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	entry, ok := b.entries[key]
+	if !ok {
+		return BlackboardEntry{}, false
+	}
+	return *entry, true
+}
+
+// Set 写入键。expectedVersion 为 AnyVersion 时不检查版本，为0时要求键不存在，
+// 否则要求当前版本与之相同，不满足时返回 ErrVersionConflict
+func (b *Blackboard) Set(key string, value interface{}, author string, expectedVersion int) (BlackboardEntry, error) {
+	if key == "" {
+		return BlackboardEntry{}, fmt.Errorf("%w: key is empty", ErrInvalidParameters)
+	}
+	valueType, err := blackboardType(value)
+	if err != nil {
+		return BlackboardEntry{}, err
+	}
+
+	b.mu.Lock()
+	previous := b.entries[key]
+	if err := checkVersion(key, previous, expectedVersion); err != nil {
+		b.mu.Unlock()
+		return BlackboardEntry{}, err
+	}
+	version := 1
+	if previous != nil {
+		if previous.Type != valueType {
+			b.mu.Unlock()
+			return BlackboardEntry{}, fmt.Errorf("%w: %s 的类型为 %s，不能写入 %s", ErrTypeMismatch, key, previous.Type, valueType)
+		}
+		version = previous.Version + 1
+	}
+	entry := &BlackboardEntry{
+		Key:       key,
+		Value:     value,
+		Type:      valueType,
+		Version:   version,
+		Author:    author,
+		UpdatedAt: time.Now(),
+	}
+	b.entries[key] = entry
+	watchers := b.matchWatchers(key)
+	b.mu.Unlock()
+
+	event := BlackboardEvent{Key: key, Entry: copyEntry(entry), Previous: copyEntry(previous), Author: author}
+	for _, fn := range watchers {
+		fn(event)
+	}
+	return *entry, nil
+}
+
+// Delete 删除键，expectedVersion 的含义与 Set 相同。键不存在时不返回错误
+func (b *Blackboard) Delete(key string, author string, expectedVersion int) error {
+	b.mu.Lock()
+	previous, ok := b.entries[key]
+	if err := checkVersion(key, previous, expectedVersion); err != nil {
+		b.mu.Unlock()
+		return err
+	}
+	if !ok {
+		b.mu.Unlock()
+		return nil
+	}
+	delete(b.entries, key)
+	watchers := b.matchWatchers(key)
+	b.mu.Unlock()
+
+	event := BlackboardEvent{Key: key, Previous: copyEntry(previous), Deleted: true, Author: author}
+	for _, fn := range watchers {
+		fn(event)
+	}
+	return nil
+}
+
+// List 返回以prefix开头的所有键值，按键排序
+func (b *Blackboard) List(prefix string) []BlackboardEntry {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	entries := make([]BlackboardEntry, 0)
+	for key, entry := range b.entries {
+		if strings.HasPrefix(key, prefix) {
+			entries = append(entries, *entry)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Key < entries[j].Key
+	})
+	return entries
+}
+
+// Watch 订阅以prefix开头的键的变更，prefix为空时订阅全部。返回的函数用于取消订阅
+//
+// 回调在写入方的协程中同步执行，不应阻塞。
+func (b *Blackboard) Watch(prefix string, fn func(BlackboardEvent)) func() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	id := b.nextID
+	b.nextID++
+	b.watchers[id] = blackboardWatcher{prefix: prefix, fn: fn}
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.watchers, id)
+	}
+}
+
+// Restore 用给定的键值替换黑板的全部内容，不触发变更事件
+func (b *Blackboard) Restore(entries []BlackboardEntry) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.entries = make(map[string]*BlackboardEntry, len(entries))
+	for i := range entries {
+		entry := entries[i]
+		b.entries[entry.Key] = &entry
+	}
+}
+
+// Clear 清空黑板，不触发变更事件
+func (b *Blackboard) Clear() {
+	b.Restore(nil)
+}
+
+// matchWatchers 返回订阅了key的回调，调用方需持有锁
+func (b *Blackboard) matchWatchers(key string) []func(BlackboardEvent) {
+	ids := make([]int, 0, len(b.watchers))
+	for id, w := range b.watchers {
+		if strings.HasPrefix(key, w.prefix) {
+			ids = append(ids, id)
+		}
+	}
+	// 按订阅顺序通知
+	sort.Ints(ids)
+	fns := make([]func(BlackboardEvent), len(ids))
+	for i, id := range ids {
+		fns[i] = b.watchers[id].fn
+	}
+	return fns
+}
+
+// checkVersion 检查期望的版本
+func checkVersion(key string, current *BlackboardEntry, expected int) error {
+	if expected == AnyVersion {
+		return nil
+	}
+	version := 0
+	if current != nil {
+		version = current.Version
+	}
+	if version != expected {
+		return fmt.Errorf("%w: %s 当前版本为 %d，期望 %d", ErrVersionConflict, key, version, expected)
+	}
+	return nil
+}
+
+// blackboardType 返回JSON值的类型，值需为JSON解码得到的类型或Go的基本类型
+func blackboardType(value interface{}) (string, error) {
+	switch value.(type) {
+	case string:
+		return "string", nil
+	case float64, float32, int, int64, int32, uint, uint64, uint32:
+		return "number", nil
+	case bool:
+		return "boolean", nil
+	case map[string]interface{}:
+		return "object", nil
+	case []interface{}:
+		return "array", nil
+	case nil:
+		return "", fmt.Errorf("%w: value is null", ErrInvalidParameters)
+	default:
+		return "", fmt.Errorf("%w: unsupported value type %T", ErrInvalidParameters, value)
+	}
+}
+
+func copyEntry(entry *BlackboardEntry) *BlackboardEntry {
+	if entry == nil {
+		return nil
+	}
+	c := *entry
+	return &c
+}
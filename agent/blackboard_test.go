@@ -0,0 +1,158 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestBlackboardCompareAndSwapRace(t *testing.T) {
+	const (
+		workers  = 8
+		versions = 20
+	)
+	b := NewBlackboard()
+
+	// 每个版本都由所有协程同时以相同的期望版本写入，只有一个成功
+	for expected := 0; expected < versions; expected++ {
+		var (
+			wg        sync.WaitGroup
+			mu        sync.Mutex
+			winners   []string
+			conflicts int
+		)
+		start := make(chan struct{})
+		for i := 0; i < workers; i++ {
+			wg.Add(1)
+			go func(author string) {
+				defer wg.Done()
+				<-start
+				entry, err := b.Set("tasks/1", author, author, expected)
+				mu.Lock()
+				defer mu.Unlock()
+				switch {
+				case err == nil:
+					if entry.Version != expected+1 {
+						t.Errorf("version %d written as %d", expected+1, entry.Version)
+					}
+					winners = append(winners, author)
+				case errors.Is(err, ErrVersionConflict):
+					conflicts++
+				default:
+					t.Error(err)
+				}
+			}(fmt.Sprintf("agent%d", i))
+		}
+		close(start)
+		wg.Wait()
+
+		if len(winners) != 1 || conflicts != workers-1 {
+			t.Fatalf("version %d: %d winners and %d conflicts, want 1 and %d", expected, len(winners), conflicts, workers-1)
+		}
+		if entry, _ := b.Get("tasks/1"); entry.Version != expected+1 || entry.Author != winners[0] || entry.Value != winners[0] {
+			t.Fatalf("version %d: entry %+v, want written by %s", expected, entry, winners[0])
+		}
+	}
+}
+
+func TestBlackboardCompareAndSwapRetry(t *testing.T) {
+	// 读取后比较并交换，冲突时重新读取，每个版本只被一次写入产生
+	const (
+		workers    = 8
+		increments = 50
+	)
+	b := NewBlackboard()
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		versions = make(map[int]int)
+	)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(author string) {
+			defer wg.Done()
+			for n := 0; n < increments; {
+				current, _ := b.Get("counter")
+				count, _ := current.Value.(float64)
+				entry, err := b.Set("counter", count+1, author, current.Version)
+				if errors.Is(err, ErrVersionConflict) {
+					continue
+				}
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				mu.Lock()
+				versions[entry.Version]++
+				mu.Unlock()
+				n++
+			}
+		}(fmt.Sprintf("agent%d", i))
+	}
+	wg.Wait()
+
+	entry, _ := b.Get("counter")
+	if entry.Version != workers*increments || entry.Value != float64(workers*increments) {
+		t.Errorf("counter = %v at version %d, want %d", entry.Value, entry.Version, workers*increments)
+	}
+	for v := 1; v <= workers*increments; v++ {
+		if versions[v] != 1 {
+			t.Errorf("version %d written %d times", v, versions[v])
+		}
+	}
+}
+
+func TestBlackboardSetToolClaim(t *testing.T) {
+	// 多个Agent通过 blackboard_set 争抢同一任务，只有一个认领成功，其余看到认领者
+	session := NewSession("board")
+	set := newBlackboardSet()
+	const workers = 8
+
+	var wg sync.WaitGroup
+	results := make([]map[string]interface{}, workers)
+	start := make(chan struct{})
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			ctx := withAgentName(WithSession(context.Background(), session), fmt.Sprintf("agent%d", i))
+			<-start
+			result, err := set.Execute(ctx, map[string]interface{}{
+				"key":              "tasks/1",
+				"value":            `{"status": "claimed"}`,
+				"expected_version": float64(0),
+			})
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			results[i] = result.(map[string]interface{})
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	winner := ""
+	for i, r := range results {
+		if r["ok"] == true {
+			if winner != "" {
+				t.Fatalf("both %s and agent%d claimed the task", winner, i)
+			}
+			winner = fmt.Sprintf("agent%d", i)
+		}
+	}
+	if winner == "" {
+		t.Fatal("no agent claimed the task")
+	}
+	for i, r := range results {
+		if r["ok"] == true {
+			continue
+		}
+		current, ok := r["current"].(BlackboardEntry)
+		if !ok || current.Author != winner || current.Version != 1 {
+			t.Errorf("agent%d: conflict result %+v, want the entry claimed by %s", i, r, winner)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"multi-agent/tools"
+)
+
+// NewBlackboardTools 创建读写会话黑板的内置工具：blackboard_get、blackboard_set 和 blackboard_list
+func NewBlackboardTools() []tools.Tool {
+	return []tools.Tool{
+		newBlackboardGet(),
+		newBlackboardSet(),
+		newBlackboardList(),
+	}
+}
+
+// blackboardFrom 返回上下文中会话的黑板
+func blackboardFrom(ctx context.Context) (*Blackboard, error) {
+	s := SessionFromContext(ctx)
+	if s == nil {
+		return nil, errors.New("黑板只能在会话中使用")
+	}
+	return s.Blackboard(), nil
+}
+
+// blackboardGet 读取黑板上的键
+type blackboardGet struct {
+	*tools.BaseTool
+}
+
+func newBlackboardGet() *blackboardGet {
+	tool := &blackboardGet{
+		BaseTool: tools.NewBaseTool("blackboard_get", "读取共享黑板上的一个键，返回值、类型、版本和最后写入者"),
+	}
+	tool.AddParameter("key", tools.ParameterSpec{
+		Type:        "string",
+		Description: "键名，如 tasks/1",
+		Required:    true,
+	})
+	return tool
+}
+
+func (t *blackboardGet) Execute(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+	board, err := blackboardFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := params["key"].(string)
+	if !ok || key == "" {
+		return nil, fmt.Errorf("invalid parameter 'key': must be a non-empty string")
+	}
+	entry, found := board.Get(key)
+	if !found {
+		return map[string]interface{}{"key": key, "found": false}, nil
+	}
+	return entry, nil
+}
+
+// blackboardSet 写入黑板上的键
+type blackboardSet struct {
+	*tools.BaseTool
+}
+
+func newBlackboardSet() *blackboardSet {
+	tool := &blackboardSet{
+		BaseTool: tools.NewBaseTool("blackboard_set",
+			"写入共享黑板上的一个键。指定 expected_version 时只有当前版本一致才会写入（0 表示键必须不存在），"+
+				"可用于认领任务：读取任务的版本后带上该版本写入，失败说明已被其他人修改"),
+	}
+	tool.AddParameter("key", tools.ParameterSpec{
+		Type:        "string",
+		Description: "键名，如 tasks/1",
+		Required:    true,
+	})
+	tool.AddParameter("value", tools.ParameterSpec{
+		Type:        "string",
+		Description: `JSON格式的值，如 "文本"、42、true、{"status":"done"}；不是合法JSON时按文本保存`,
+		Required:    true,
+	})
+	tool.AddParameter("expected_version", tools.ParameterSpec{
+		Type:        "number",
+		Description: "期望的当前版本，不指定时直接覆盖",
+	})
+	return tool
+}
+
+func (t *blackboardSet) Execute(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+	board, err := blackboardFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := params["key"].(string)
+	if !ok || key == "" {
+		return nil, fmt.Errorf("invalid parameter 'key': must be a non-empty string")
+	}
+	value, ok := params["value"]
+	if !ok || value == nil {
+		return nil, fmt.Errorf("invalid parameter 'value': required")
+	}
+	if s, isString := value.(string); isString {
+		value = parseBlackboardValue(s)
+	}
+	expected := AnyVersion
+	if v, ok := params["expected_version"].(float64); ok {
+		expected = int(v)
+	}
+
+	entry, err := board.Set(key, value, AgentNameFromContext(ctx), expected)
+	if errors.Is(err, ErrVersionConflict) || errors.Is(err, ErrTypeMismatch) {
+		// 冲突是正常的协作结果，返回给模型而不是中断执行
+		result := map[string]interface{}{"ok": false, "error": err.Error()}
+		if current, found := board.Get(key); found {
+			result["current"] = current
+		}
+		return result, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"ok": true, "entry": entry}, nil
+}
+
+// parseBlackboardValue 解析JSON值，不是合法JSON时按文本处理
+func parseBlackboardValue(s string) interface{} {
+	var v interface{}
+	if err := json.Unmarshal([]byte(s), &v); err != nil || v == nil {
+		return s
+	}
+	return v
+}
+
+// blackboardList 列出黑板上的键
+type blackboardList struct {
+	*tools.BaseTool
+}
+
+func newBlackboardList() *blackboardList {
+	tool := &blackboardList{
+		BaseTool: tools.NewBaseTool("blackboard_list", "列出共享黑板上的键值，可按前缀过滤，如 tasks/"),
+	}
+	tool.AddParameter("prefix", tools.ParameterSpec{
+		Type:        "string",
+		Description: "键名前缀，不指定时列出全部",
+	})
+	return tool
+}
+
+func (t *blackboardList) Execute(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+	board, err := blackboardFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+	prefix, _ := params["prefix"].(string)
+	return board.List(prefix), nil
+}
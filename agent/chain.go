@@ -2,11 +2,16 @@ package agent
 
 import (
   "context"
+  "sync"
 )
 
 type Chain struct {
-  agents    []Agent
-  maxRounds int
+  agents     []Agent
+  maxRounds  int
+  until      func(output string) bool // 终止条件，成立时提前结束
+
+  mu         sync.Mutex
+  iterations int // 最近一次执行实际完成的轮数
 }
 
 func NewChain(maxRounds int, agents ...Agent) *Chain {
@@ -16,8 +21,28 @@ func NewChain(maxRounds int, agents ...Agent) *Chain {
   }
 }
 
+// SetUntil 设置终止条件，每轮结束后检查最后一个Agent的输出，成立时不再继续
+func (c *Chain) SetUntil(until func(output string) bool) {
+  c.until = until
+}
+
+// Iterations 返回最近一次执行实际完成的轮数
+func (c *Chain) Iterations() int {
+  c.mu.Lock()
+  defer c.mu.Unlock()
+  return c.iterations
+}
+
 func (c *Chain) Execute(ctx context.Context, input string) (string, error) {
   result := input
+  iterations := 0
+  ctx = sessionContext(ctx)
+  // 并发执行时各自计数，结束后记录为最近一次执行的轮数
+  defer func() {
+    c.mu.Lock()
+    c.iterations = iterations
+    c.mu.Unlock()
+  }()
   
   for round := 0; round < c.maxRounds; round++ {
     for _, agent := range c.agents {
@@ -27,6 +52,10 @@ func (c *Chain) Execute(ctx context.Context, input string) (string, error) {
         return "", err
       }
     }
+    iterations++
+    if c.until != nil && c.until(result) {
+      break
+    }
   }
   
   return result, nil
@@ -0,0 +1,60 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestChainUntil(t *testing.T) {
+	writer := newStubAgent("writer", func(call int, input string) string { return input + "+" })
+	editor := newStubAgent("editor", func(call int, input string) string {
+		if call == 2 {
+			return "DONE"
+		}
+		return input
+	})
+
+	c := NewChain(5, writer, editor)
+	c.SetUntil(func(output string) bool { return strings.Contains(output, "DONE") })
+	output, err := c.Execute(context.Background(), "x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output != "DONE" || c.Iterations() != 2 {
+		t.Errorf("output %q after %d iterations, want DONE after 2", output, c.Iterations())
+	}
+	if writer.calls() != 2 || editor.calls() != 2 {
+		t.Errorf("writer ran %d times, editor %d times, want 2", writer.calls(), editor.calls())
+	}
+
+	// 条件不成立时执行到最大轮数
+	c.SetUntil(func(output string) bool { return false })
+	if _, err := c.Execute(context.Background(), "x"); err != nil {
+		t.Fatal(err)
+	}
+	if c.Iterations() != 5 {
+		t.Errorf("iterations = %d, want 5", c.Iterations())
+	}
+}
+
+func TestChainConcurrentExecute(t *testing.T) {
+	c := NewChain(3, newStubAgent("writer", constant("草稿")))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := c.Execute(context.Background(), "x"); err != nil {
+				t.Error(err)
+			}
+			c.Iterations()
+		}()
+	}
+	wg.Wait()
+	if c.Iterations() != 3 {
+		t.Errorf("iterations = %d, want 3", c.Iterations())
+	}
+}
@@ -0,0 +1,238 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"multi-agent/oneapi"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Checkpoint 记录一次运行的进度，用于崩溃或修复后继续执行
+type Checkpoint struct {
+	RunID     string                          `json:"run_id"`
+	Input     string                          `json:"input"`                // 原始输入
+	Round     int                             `json:"round"`                // 当前进行中的轮次
+	Rounds    []RoundResult                   `json:"rounds"`               // 已完成轮次的结果
+	Current   RoundResult                     `json:"current"`              // 当前轮次中已完成节点的结果
+	Memories  map[string][]oneapi.ChatMessage `json:"memories"`             // 各Agent的对话记忆
+	Board     []BlackboardEntry               `json:"blackboard,omitempty"` // 黑板内容
+	Done      bool                            `json:"done"`                 // 是否已全部完成
+	UpdatedAt time.Time                       `json:"updated_at"`           // 最后更新时间
+}
+
+// CheckpointStore 检查点存储接口
+type CheckpointStore interface {
+	// Save 保存检查点，已存在时覆盖
+	Save(cp *Checkpoint) error
+	// Load 加载指定运行的检查点，不存在时返回 ErrCheckpointNotFound
+	Load(runID string) (*Checkpoint, error)
+	// Delete 删除指定运行的检查点
+	Delete(runID string) error
+}
+
+// MemoryCheckpointStore 基于内存的检查点存储
+type MemoryCheckpointStore struct {
+	checkpoints map[string][]byte
+	mu          sync.RWMutex
+}
+
+// NewMemoryCheckpointStore 创建内存检查点存储
+func NewMemoryCheckpointStore() *MemoryCheckpointStore {
+	return &MemoryCheckpointStore{
+		checkpoints: make(map[string][]byte),
+	}
+}
+
+func (s *MemoryCheckpointStore) Save(cp *Checkpoint) error {
+	// 序列化保存，避免与运行中的数据共享引用
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return fmt.Errorf("marshal checkpoint failed: %w", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.checkpoints[cp.RunID] = data
+	return nil
+}
+
+func (s *MemoryCheckpointStore) Load(runID string) (*Checkpoint, error) {
+	s.mu.RLock()
+	data, ok := s.checkpoints[runID]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrCheckpointNotFound, runID)
+	}
+
+	var cp Checkpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return nil, fmt.Errorf("decode checkpoint failed: %w", err)
+	}
+	return &cp, nil
+}
+
+func (s *MemoryCheckpointStore) Delete(runID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.checkpoints, runID)
+	return nil
+}
+
+// FileCheckpointStore 基于文件的检查点存储，每次运行对应目录下的一个JSON文件
+type FileCheckpointStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewFileCheckpointStore 创建文件检查点存储，目录不存在时自动创建
+func NewFileCheckpointStore(dir string) (*FileCheckpointStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create checkpoint dir failed: %w", err)
+	}
+	return &FileCheckpointStore{dir: dir}, nil
+}
+
+// path 返回检查点文件路径，运行ID经过转义，不会逃出存储目录
+func (s *FileCheckpointStore) path(runID string) string {
+	return filepath.Join(s.dir, url.PathEscape(runID)+".json")
+}
+
+func (s *FileCheckpointStore) Save(cp *Checkpoint) error {
+	data, err := json.MarshalIndent(cp, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal checkpoint failed: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// 先写临时文件再重命名，避免崩溃时留下不完整的检查点
+	tmp := s.path(cp.RunID) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("write checkpoint failed: %w", err)
+	}
+	if err := os.Rename(tmp, s.path(cp.RunID)); err != nil {
+		return fmt.Errorf("rename checkpoint failed: %w", err)
+	}
+	return nil
+}
+
+func (s *FileCheckpointStore) Load(runID string) (*Checkpoint, error) {
+	s.mu.Lock()
+	data, err := os.ReadFile(s.path(runID))
+	s.mu.Unlock()
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("%w: %s", ErrCheckpointNotFound, runID)
+		}
+		return nil, fmt.Errorf("read checkpoint failed: %w", err)
+	}
+
+	var cp Checkpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return nil, fmt.Errorf("decode checkpoint failed: %w", err)
+	}
+	return &cp, nil
+}
+
+func (s *FileCheckpointStore) Delete(runID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := os.Remove(s.path(runID)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("delete checkpoint failed: %w", err)
+	}
+	return nil
+}
+
+var runSeq uint64
+
+// newRunID 生成新的运行ID
+func newRunID() string {
+	return fmt.Sprintf("run_%d_%d", time.Now().UnixNano(), atomic.AddUint64(&runSeq, 1))
+}
+
+// checkpointer 在执行过程中记录进度并写入存储，store为空时只记录不保存
+type checkpointer struct {
+	store   CheckpointStore
+	cp      *Checkpoint
+	session *Session       // 运行所在的会话
+	agents  func() []Agent // 用于获取需要保存记忆的Agent
+	mu      sync.Mutex
+}
+
+func newCheckpointer(store CheckpointStore, cp *Checkpoint, session *Session, agents func() []Agent) *checkpointer {
+	if cp.Current == nil {
+		cp.Current = make(RoundResult)
+	}
+	if session != nil {
+		session.Recorder().begin(cp.RunID, cp.Input, cp.Rounds)
+	}
+	return &checkpointer{store: store, cp: cp, session: session, agents: agents}
+}
+
+// completed 返回当前轮次中已完成节点的结果
+func (c *checkpointer) completed(name string) (*AgentResult, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	result, ok := c.cp.Current[name]
+	return result, ok
+}
+
+// record 记录节点结果并保存检查点
+func (c *checkpointer) record(name string, result *AgentResult) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cp.Current[name] = result
+	c.save()
+}
+
+// completeRound 记录一轮完成并进入下一轮
+func (c *checkpointer) completeRound(results RoundResult) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cp.Rounds = append(c.cp.Rounds, results)
+	c.cp.Round = len(c.cp.Rounds)
+	c.cp.Current = make(RoundResult)
+	c.save()
+	if c.session != nil {
+		c.session.Recorder().completeRound(results)
+	}
+}
+
+// finish 标记运行完成
+func (c *checkpointer) finish() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cp.Done = true
+	c.save()
+}
+
+// save 保存检查点，调用方需持有锁
+func (c *checkpointer) save() {
+	if c.store == nil {
+		return
+	}
+	c.cp.Memories = c.session.Snapshot(c.agents())
+	c.cp.Board = c.session.Blackboard().List("")
+	c.cp.UpdatedAt = time.Now()
+	if err := c.store.Save(c.cp); err != nil {
+		log.Printf("保存检查点失败: %v", err)
+	}
+}
+
+// loadCheckpoint 从存储加载检查点
+func loadCheckpoint(ctx context.Context, store CheckpointStore, runID string) (*Checkpoint, error) {
+	if store == nil {
+		return nil, fmt.Errorf("%w: checkpoint store not set", ErrCheckpointNotFound)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return store.Load(runID)
+}
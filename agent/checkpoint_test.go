@@ -0,0 +1,96 @@
+package agent
+
+import (
+	"context"
+	"testing"
+)
+
+func TestGroupResumeFromCheckpoint(t *testing.T) {
+	fileStore, err := NewFileCheckpointStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	stores := map[string]CheckpointStore{
+		"memory": NewMemoryCheckpointStore(),
+		"file":   fileStore,
+	}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			// 两个Agent三轮，第4次请求（第二轮的第二个Agent）失败，中断运行
+			llm := &fakeLLM{failAt: 4}
+			useFakeLLM(t, llm)
+			newGroup := func() *Group {
+				g := NewGroup(3, false, nil)
+				g.AddAgent(NewAgent("a", "领域a", "专家a"))
+				g.AddAgent(NewAgent("b", "领域b", "专家b"))
+				g.SetCheckpointStore(store)
+				return g
+			}
+
+			g := newGroup()
+			session := NewSession("interrupted")
+			if _, err := g.ExecuteDetailed(WithSession(context.Background(), session), "主题"); err == nil {
+				t.Fatal("run was not interrupted")
+			}
+			cp, err := store.Load(g.RunID())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cp.Done || len(cp.Rounds) != 1 || len(cp.Current) != 1 {
+				t.Fatalf("checkpoint has %d rounds and %d agents of the current round, want 1 and 1",
+					len(cp.Rounds), len(cp.Current))
+			}
+
+			// 文件存储模拟进程重启：新的Group和会话；内存存储在原会话中继续
+			resumed := g
+			if name == "file" {
+				resumed = newGroup()
+				session = NewSession("restarted")
+			}
+			rounds, err := resumed.Resume(WithSession(context.Background(), session), g.RunID())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(rounds) != 3 {
+				t.Fatalf("got %d rounds, want 3", len(rounds))
+			}
+			for i, round := range rounds {
+				if len(round) != 2 {
+					t.Errorf("round %d has %d results, want 2", i+1, len(round))
+				}
+			}
+
+			// 已完成的Agent不会重复执行：每个成功的请求只出现一次
+			if len(llm.answered) != 6 {
+				t.Errorf("got %d successful requests, want 6", len(llm.answered))
+			}
+			seen := make(map[string]bool)
+			for _, req := range llm.answered {
+				if seen[req] {
+					t.Errorf("request executed twice: %q", req)
+				}
+				seen[req] = true
+			}
+
+			// 记忆中的用户消息（包括同步的其他Agent发言）不重复，每个Agent回答三次
+			for _, a := range resumed.Agents() {
+				users := make(map[string]bool)
+				answers := 0
+				for _, msg := range session.Memory(a.Name()).GetHistory() {
+					switch msg.Role {
+					case "user":
+						if users[msg.Content] {
+							t.Errorf("%s: user message duplicated in memory: %q", a.Name(), msg.Content)
+						}
+						users[msg.Content] = true
+					case "assistant":
+						answers++
+					}
+				}
+				if answers != 3 {
+					t.Errorf("%s: %d answers in memory, want 3", a.Name(), answers)
+				}
+			}
+		})
+	}
+}
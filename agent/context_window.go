@@ -0,0 +1,129 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"multi-agent/oneapi"
+)
+
+// TrimStrategy 消息超出Token预算时的裁剪策略
+//
+// 实现应保留开头的系统提示词和最新的消息，只处理中间较早的对话。
+type TrimStrategy interface {
+	Trim(messages []oneapi.ChatMessage, budget int) []oneapi.ChatMessage
+}
+
+// DropMiddleStrategy 丢弃中间较早的消息，直到满足预算
+type DropMiddleStrategy struct{}
+
+// Trim 实现 TrimStrategy
+func (DropMiddleStrategy) Trim(messages []oneapi.ChatMessage, budget int) []oneapi.ChatMessage {
+	if oneapi.CountMessagesTokens(messages) <= budget {
+		return messages
+	}
+	head, rest := splitSystemMessages(messages)
+	return dropMiddle(head, rest, budget)
+}
+
+// CompressMiddleStrategy 先压缩中间较长消息的内容（保留首尾），仍超出预算时再丢弃较早的消息
+type CompressMiddleStrategy struct {
+	MaxChars   int // 压缩后每条消息保留的最大字符数，默认200
+	KeepLatest int // 不压缩的最新消息条数，默认4
+}
+
+// Trim 实现 TrimStrategy
+func (s CompressMiddleStrategy) Trim(messages []oneapi.ChatMessage, budget int) []oneapi.ChatMessage {
+	if oneapi.CountMessagesTokens(messages) <= budget {
+		return messages
+	}
+	maxChars, keepLatest := s.MaxChars, s.KeepLatest
+	if maxChars <= 0 {
+		maxChars = 200
+	}
+	if keepLatest <= 0 {
+		keepLatest = 4
+	}
+
+	head, rest := splitSystemMessages(messages)
+	compressed := make([]oneapi.ChatMessage, len(rest))
+	copy(compressed, rest)
+	for i := 0; i < len(compressed)-keepLatest; i++ {
+		compressed[i].Content = compressText(compressed[i].Content, maxChars)
+	}
+	if oneapi.CountMessagesTokens(head)+oneapi.CountMessagesTokens(compressed) <= budget {
+		return append(append([]oneapi.ChatMessage(nil), head...), compressed...)
+	}
+	return dropMiddle(head, compressed, budget)
+}
+
+// splitSystemMessages 拆分开头的系统消息和其余消息
+func splitSystemMessages(messages []oneapi.ChatMessage) (head, rest []oneapi.ChatMessage) {
+	i := 0
+	for i < len(messages) && messages[i].Role == "system" {
+		i++
+	}
+	return messages[:i], messages[i:]
+}
+
+// dropMiddle 保留head和rest中最新的消息，丢弃较早的消息并插入省略提示。
+// 不会留下缺少对应工具调用的工具结果
+func dropMiddle(head, rest []oneapi.ChatMessage, budget int) []oneapi.ChatMessage {
+	if len(rest) == 0 {
+		return head
+	}
+	notice := oneapi.ChatMessage{Role: "system", Content: fmt.Sprintf("（已省略%d条较早的对话）", len(rest))}
+	available := budget - oneapi.CountMessagesTokens(head) - oneapi.CountMessageTokens(notice)
+
+	// 最新的消息必须保留，若以工具结果结尾则一并保留对应的工具调用
+	start := len(rest) - 1
+	for start > 0 && rest[start].Role == "tool" {
+		start--
+	}
+	used := 0
+	for _, msg := range rest[start:] {
+		used += oneapi.CountMessageTokens(msg)
+	}
+	for start > 0 {
+		cost := oneapi.CountMessageTokens(rest[start-1])
+		if used+cost > available {
+			break
+		}
+		used += cost
+		start--
+	}
+	// 工具结果必须跟在对应的工具调用之后
+	for start < len(rest)-1 && rest[start].Role == "tool" {
+		start++
+	}
+
+	trimmed := make([]oneapi.ChatMessage, 0, len(head)+1+len(rest)-start)
+	trimmed = append(trimmed, head...)
+	if start > 0 {
+		notice.Content = fmt.Sprintf("（已省略%d条较早的对话）", start)
+		trimmed = append(trimmed, notice)
+	}
+	return append(trimmed, rest[start:]...)
+}
+
+// compressText 将超过maxChars的文本压缩为首尾两部分
+func compressText(text string, maxChars int) string {
+	runes := []rune(text)
+	if len(runes) <= maxChars {
+		return text
+	}
+	half := maxChars / 2
+	return fmt.Sprintf("%s……（省略%d字）……%s",
+		string(runes[:half]), len(runes)-2*half, string(runes[len(runes)-half:]))
+}
+
+// countToolDefTokens 估算工具定义占用的Token数量
+func countToolDefTokens(defs []oneapi.ToolDef) int {
+	if len(defs) == 0 {
+		return 0
+	}
+	data, err := json.Marshal(defs)
+	if err != nil {
+		return 0
+	}
+	return oneapi.CountTokens(string(data))
+}
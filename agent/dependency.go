@@ -9,20 +9,68 @@ import (
 )
 
 type DependencyGraph struct {
+	nodes     map[string]*Node
+	maxRounds int            // 整体最大轮数
+	loops     []*Loop        // 循环边
+	callback  OutputCallback // 添加回调接口
+	mu        sync.RWMutex   // 只保护拓扑和配置，执行期间不持有
+	// 检查点
+	store CheckpointStore
+	runID string // 最近一次执行的运行ID
+	// 失败处理
+	policy     FailurePolicy
+	maxRetries int
+}
+
+// graphRun 依赖图的一次执行，持有执行开始时的拓扑和配置快照以及本次运行的状态，
+// 同一个依赖图可以同时进行多次执行
+type graphRun struct {
+	graph        *DependencyGraph
 	nodes        map[string]*Node
-	maxRounds    int                 // 整体最大轮数
-	roundResults []map[string]string // 每轮的结果
-	callback     OutputCallback      // 添加回调接口
-	mu           sync.RWMutex
-	// 添加memory统一管理
-	memory *MemoryManager
+	maxRounds    int
+	roundResults []RoundResult // 每轮的结果
+	loops        []*Loop
+	callback     OutputCallback
+	progress     *checkpointer
+	policy       FailurePolicy
+	maxRetries   int
 }
 
 type Node struct {
 	agent        Agent
-	dependencies []*Node
-	capability   float64 // 能力分数，用于选择最合适的Agent
-	visited      bool    // 用于追踪是否已参与当前轮次讨论
+	dependencies []*Edge
+	router       RouteFunc // 路由函数，执行后决定哪些下游分支继续执行
+	capability   float64   // 能力分数，用于选择最合适的Agent
+}
+
+// Edge 依赖边，指向上游节点
+type Edge struct {
+	from      *Node
+	label     string        // 条件说明，用于展示
+	condition EdgeCondition // 为空表示无条件依赖
+}
+
+// EdgeCondition 边的条件谓词，参数为已完成的上游Agent输出
+type EdgeCondition func(outputs map[string]string) bool
+
+// RouteFunc 路由函数，根据节点输出返回需要继续执行的下游Agent名称
+type RouteFunc func(output string) []string
+
+// OutputContains 当指定Agent的输出包含任一关键词时条件成立（不区分大小写）
+func OutputContains(agentName string, keywords ...string) EdgeCondition {
+	return func(outputs map[string]string) bool {
+		output, ok := outputs[agentName]
+		if !ok {
+			return false
+		}
+		output = strings.ToLower(output)
+		for _, keyword := range keywords {
+			if strings.Contains(output, strings.ToLower(keyword)) {
+				return true
+			}
+		}
+		return false
+	}
 }
 
 func NewDependencyGraph(maxRounds int, callback OutputCallback) *DependencyGraph {
@@ -30,11 +78,9 @@ func NewDependencyGraph(maxRounds int, callback OutputCallback) *DependencyGraph
 		maxRounds = 1 // 确保至少有一轮讨论
 	}
 	return &DependencyGraph{
-		nodes:        make(map[string]*Node),
-		maxRounds:    maxRounds,
-		roundResults: make([]map[string]string, 0),
-		callback:     callback,
-		memory:       globalMemoryManager,
+		nodes:     make(map[string]*Node),
+		maxRounds: maxRounds,
+		callback:  callback,
 	}
 }
 
@@ -44,16 +90,41 @@ func (d *DependencyGraph) AddAgent(agent Agent) {
 
 	// 基于Agent的能力计算初始分数
 	capability := calculateAgentCapability(agent)
+	setAgentCallback(agent, d.callback)
 
 	d.nodes[agent.Name()] = &Node{
 		agent:        agent,
-		dependencies: make([]*Node, 0),
+		dependencies: make([]*Edge, 0),
 		capability:   capability,
-		visited:      false,
 	}
 }
 
 func (d *DependencyGraph) AddDependency(dependent, dependency string) error {
+	return d.addEdge(dependent, dependency, "", nil)
+}
+
+// AddConditionalDependency 添加条件依赖：dependency执行完成且condition成立时，dependent才会执行
+func (d *DependencyGraph) AddConditionalDependency(dependent, dependency, label string, condition EdgeCondition) error {
+	if condition == nil {
+		return fmt.Errorf("%w: condition is nil", ErrInvalidDependency)
+	}
+	return d.addEdge(dependent, dependency, label, condition)
+}
+
+// SetRouter 为节点设置路由函数，节点执行后只有被选中的下游Agent会继续执行
+func (d *DependencyGraph) SetRouter(name string, route RouteFunc) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	node, ok := d.nodes[name]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrAgentNotFound, name)
+	}
+	node.router = route
+	return nil
+}
+
+func (d *DependencyGraph) addEdge(dependent, dependency, label string, condition EdgeCondition) error {
 	d.mu.Lock()
 	defer d.mu.Unlock()
 
@@ -67,72 +138,228 @@ func (d *DependencyGraph) AddDependency(dependent, dependency string) error {
 		return fmt.Errorf("%w: %s", ErrAgentNotFound, dependency)
 	}
 
-	// 检查是否已存在该依赖
-	for _, dep := range depNode.dependencies {
-		if dep.agent.Name() == dependency {
+	// 检查是否已存在该依赖，存在时更新条件
+	for _, edge := range depNode.dependencies {
+		if edge.from.agent.Name() == dependency {
+			if condition != nil {
+				edge.label = label
+				edge.condition = condition
+			}
 			return nil
 		}
 	}
 
-	depNode.dependencies = append(depNode.dependencies, depOnNode)
+	depNode.dependencies = append(depNode.dependencies, &Edge{
+		from:      depOnNode,
+		label:     label,
+		condition: condition,
+	})
 	return nil
 }
 
-// Execute 执行整个图的对话
+// Execute 执行整个图的对话，返回每轮成功执行的Agent输出
 func (d *DependencyGraph) Execute(ctx context.Context, input string) ([]map[string]string, error) {
-	d.mu.Lock()
+	rounds, err := d.ExecuteDetailed(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	return roundContents(rounds), nil
+}
 
-	// 为所有Agent设置回调
-	for _, node := range d.nodes {
-		if expertAgent, ok := node.agent.(*ExpertAgent); ok {
-			expertAgent.SetCallback(d.callback)
+// ExecuteDetailed 执行整个图的对话，返回每轮各Agent的执行状态、输出和错误。
+// ctx中没有会话时使用新的会话，多次执行之间不共享记忆
+func (d *DependencyGraph) ExecuteDetailed(ctx context.Context, input string) ([]RoundResult, error) {
+	run, store := d.newRun()
+	runID := newRunID()
+	d.setRunID(runID)
+	ctx = sessionContext(ctx)
+	return run.run(ctx, store, &Checkpoint{RunID: runID, Input: input})
+}
+
+// newRun 复制当前的拓扑和配置，创建一次执行，之后修改图不影响进行中的执行
+func (d *DependencyGraph) newRun() (*graphRun, CheckpointStore) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	copies := make(map[*Node]*Node, len(d.nodes))
+	nodes := make(map[string]*Node, len(d.nodes))
+	for name, node := range d.nodes {
+		c := *node
+		copies[node] = &c
+		nodes[name] = &c
+	}
+	for _, c := range copies {
+		dependencies := make([]*Edge, len(c.dependencies))
+		for i, edge := range c.dependencies {
+			e := *edge
+			e.from = copies[edge.from]
+			dependencies[i] = &e
 		}
+		c.dependencies = dependencies
+	}
+	loops := make([]*Loop, len(d.loops))
+	for i, loop := range d.loops {
+		l := *loop
+		l.from, l.to = copies[loop.from], copies[loop.to]
+		loops[i] = &l
 	}
 
+	return &graphRun{
+		graph:      d,
+		nodes:      nodes,
+		maxRounds:  d.maxRounds,
+		loops:      loops,
+		callback:   d.callback,
+		policy:     d.policy,
+		maxRetries: d.maxRetries,
+	}, d.store
+}
+
+func (d *DependencyGraph) setRunID(runID string) {
+	d.mu.Lock()
 	defer d.mu.Unlock()
+	d.runID = runID
+}
 
-	// 初始化结果存储
-	d.roundResults = make([]map[string]string, 0, d.maxRounds)
-	for i := range d.roundResults {
-		d.roundResults[i] = make(map[string]string)
+// SetCallback 设置输出回调，同时更新图中所有Agent的回调
+func (d *DependencyGraph) SetCallback(callback OutputCallback) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.callback = callback
+	for _, node := range d.nodes {
+		setAgentCallback(node.agent, callback)
 	}
+}
 
-	// 第一轮：按依赖顺序执行
-	firstRoundResults, err := d.executeFirstRound(ctx, input)
+// Agents 返回图中的Agent，按名称排序
+func (d *DependencyGraph) Agents() []Agent {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	agents := d.agentList()
+	sort.Slice(agents, func(i, j int) bool {
+		return agents[i].Name() < agents[j].Name()
+	})
+	return agents
+}
+
+// SetMaxRounds 设置最大轮数
+func (d *DependencyGraph) SetMaxRounds(maxRounds int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if maxRounds < 1 {
+		maxRounds = 1
+	}
+	d.maxRounds = maxRounds
+}
+
+// SetCheckpointStore 设置检查点存储，每个节点完成后都会保存进度
+func (d *DependencyGraph) SetCheckpointStore(store CheckpointStore) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.store = store
+}
+
+// SetFailurePolicy 设置节点执行失败时的处理策略，maxRetries 仅对 RetryThenContinue 生效
+func (d *DependencyGraph) SetFailurePolicy(policy FailurePolicy, maxRetries int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.policy = policy
+	d.maxRetries = maxRetries
+}
+
+// RunID 返回最近一次执行的运行ID，可用于 Resume
+func (d *DependencyGraph) RunID() string {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.runID
+}
+
+// Resume 从检查点继续执行指定的运行，已完成的节点不会重复执行
+func (d *DependencyGraph) Resume(ctx context.Context, runID string) ([]RoundResult, error) {
+	run, store := d.newRun()
+	cp, err := loadCheckpoint(ctx, store, runID)
 	if err != nil {
 		return nil, err
 	}
-	d.roundResults = append(d.roundResults, firstRoundResults)
+	d.setRunID(runID)
 
-	// 后续轮次：选择最合适的Agent，并传递完整上下文
-	currentInput := input + "\n\n" + d.combineRoundResults(0, firstRoundResults)
+	if cp.Done {
+		return cp.Rounds, nil
+	}
+
+	ctx = sessionContext(ctx)
+	SessionFromContext(ctx).Restore(run.agentList(), cp.Memories)
+	SessionFromContext(ctx).Blackboard().Restore(cp.Board)
+	return run.run(ctx, store, cp)
+}
+
+// run 从检查点记录的位置开始执行
+func (d *graphRun) run(ctx context.Context, store CheckpointStore, cp *Checkpoint) ([]RoundResult, error) {
+	d.progress = newCheckpointer(store, cp, SessionFromContext(ctx), d.agentList)
+	d.callback = callbackFor(ctx, d.callback)
+	input := cp.Input
+
+	// 初始化结果存储，恢复已完成的轮次
+	d.roundResults = make([]RoundResult, 0, d.maxRounds)
+	d.roundResults = append(d.roundResults, cp.Rounds...)
 
-	for round := 1; round < d.maxRounds; round++ {
-		roundResults, err := d.executeSubsequentRound(ctx, currentInput, round)
+	// 第一轮：按依赖顺序执行
+	if len(d.roundResults) == 0 {
+		firstRoundResults, err := d.executeFirstRound(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		d.roundResults = append(d.roundResults, firstRoundResults)
+		d.progress.completeRound(firstRoundResults)
+	}
+
+	// 后续轮次：选择最合适的Agent，并传递完整上下文
+	for round := len(d.roundResults); round < d.maxRounds; round++ {
+		// 输入包含所有历史信息
+		currentInput := input + "\n\n" + d.combineRoundResults(round-1, d.roundResults[round-1])
+		roundResults, err := d.executeSubsequentRound(ctx, currentInput, round, d.roundResults[0].Skipped())
 		if err != nil {
 			return nil, err
 		}
 
 		d.roundResults = append(d.roundResults, roundResults)
-		// 更新输入，包含所有历史信息
-		currentInput = input + "\n\n" + d.combineRoundResults(round, roundResults)
+		d.progress.completeRound(roundResults)
 	}
+	d.progress.finish()
 
 	// 通知所有执行完成
 	if d.callback != nil {
-		d.callback.OnAllComplete(d.roundResults)
+		d.callback.OnAllComplete(roundContents(d.roundResults))
 	}
 
-	// 执行完成后清理Memory
-	d.memory.ClearTaskMemory(getOrCreateTaskID())
-
 	return d.roundResults, nil
 }
 
+// agentList 返回图中所有Agent
+func (d *DependencyGraph) agentList() []Agent {
+	agents := make([]Agent, 0, len(d.nodes))
+	for _, node := range d.nodes {
+		agents = append(agents, node.agent)
+	}
+	return agents
+}
+
+// agentList 返回本次执行中的所有Agent
+func (d *graphRun) agentList() []Agent {
+	agents := make([]Agent, 0, len(d.nodes))
+	for _, node := range d.nodes {
+		agents = append(agents, node.agent)
+	}
+	return agents
+}
+
 // executeFirstRound 执行第一轮讨论（按依赖顺序）
-func (d *DependencyGraph) executeFirstRound(ctx context.Context, input string) (map[string]string, error) {
-	results := make(map[string]string)
+func (d *graphRun) executeFirstRound(ctx context.Context, input string) (RoundResult, error) {
+	results := make(RoundResult)
+	outputs := make(map[string]string)
+	routes := make(map[string][]string)
 	visited := make(map[string]bool)
+	topic := input
 
 	if d.maxRounds > 1 {
 		// 构建第一轮的提示词
@@ -144,9 +371,83 @@ func (d *DependencyGraph) executeFirstRound(ctx context.Context, input string) (
   请根据你的专业角度提供意见。`, input)
 	}
 
-	// 重置所有节点的访问状态
-	for _, node := range d.nodes {
-		node.visited = false
+	// 执行单个节点的第iteration次迭代，所有入边都未激活时跳过
+	run := func(node *Node, prompt string, iteration int) error {
+		name := node.agent.Name()
+
+		// 断点恢复：直接使用检查点中已完成的结果
+		if prev, ok := d.progress.completed(name); ok &&
+			(prev.Iterations >= iteration || prev.Status == StatusSkipped && iteration == 1) {
+			results[name] = prev
+			if prev.Status == StatusSuccess {
+				outputs[name] = prev.Content
+				if node.router != nil {
+					routes[name] = node.router(prev.Content)
+				}
+			}
+			return nil
+		}
+
+		if reason, skip := d.shouldSkip(node, outputs, routes); skip {
+			if prev, ok := results[name]; ok && iteration > 1 && prev.Status == StatusSuccess {
+				// 循环的后续迭代中未被激活时保留上一次成功的结果和执行次数
+				return nil
+			}
+			results[name] = &AgentResult{Status: StatusSkipped, Reason: reason}
+			delete(outputs, name)
+			d.progress.record(name, results[name])
+			return nil
+		}
+
+		result, err := executeWithPolicy(ctx, node.agent, prompt, d.policy, d.maxRetries)
+		if err != nil {
+			return fmt.Errorf("agent %s execution failed: %w", name, err)
+		}
+
+		// 按失败策略继续时，下游节点会因上游未执行而跳过
+		if result.Status == StatusFailed {
+			results[name] = result
+			delete(outputs, name)
+			d.progress.record(name, result)
+			return nil
+		}
+
+		if prev, ok := results[name]; ok && prev.Status == StatusSuccess {
+			result.Iterations = prev.Iterations + 1
+			result.Duration += prev.Duration
+			result.Tokens += prev.Tokens
+		}
+		results[name] = result
+		outputs[name] = result.Content
+		if node.router != nil {
+			routes[name] = node.router(result.Content)
+		}
+		d.progress.record(name, results[name])
+
+		return nil
+	}
+
+	// 执行以该节点结尾的循环，直到终止条件成立或达到最大迭代次数
+	runLoops := func(node *Node) error {
+		for _, loop := range d.loops {
+			last, ok := results[node.agent.Name()]
+			if loop.from != node || !ok || last.Status != StatusSuccess {
+				continue
+			}
+			body := d.loopBody(loop)
+			for iteration := last.Iterations + 1; iteration <= loop.maxIterations; iteration++ {
+				if loop.until != nil && loop.until(outputs) {
+					break
+				}
+				prompt := d.buildLoopPrompt(topic, iteration, body, outputs)
+				for _, bodyNode := range body {
+					if err := run(bodyNode, prompt, iteration); err != nil {
+						return err
+					}
+				}
+			}
+		}
+		return nil
 	}
 
 	// 按依赖顺序执行
@@ -155,24 +456,20 @@ func (d *DependencyGraph) executeFirstRound(ctx context.Context, input string) (
 		if visited[node.agent.Name()] {
 			return nil
 		}
+		visited[node.agent.Name()] = true
 
 		// 先执行依赖
-		for _, dep := range node.dependencies {
-			if err := execute(dep); err != nil {
+		for _, edge := range node.dependencies {
+			if err := execute(edge.from); err != nil {
 				return err
 			}
 		}
+
 		// 执行当前节点
-		result, err := node.agent.Execute(ctx, input)
-		if err != nil {
+		if err := run(node, input, 1); err != nil {
 			return err
 		}
-
-		results[node.agent.Name()] = result
-		visited[node.agent.Name()] = true
-		node.visited = true
-
-		return nil
+		return runLoops(node)
 	}
 
 	// 如果没有明确的依赖关系，按能力分数排序执行
@@ -193,21 +490,64 @@ func (d *DependencyGraph) executeFirstRound(ctx context.Context, input string) (
 	}
 
 	if d.callback != nil {
-		d.callback.OnRoundComplete(0, results)
+		d.callback.OnRoundComplete(0, results.Contents())
 	}
 
 	return results, nil
 }
 
+// shouldSkip 判断节点是否应被跳过：存在依赖且没有任何一条入边被激活
+func (d *graphRun) shouldSkip(node *Node, outputs map[string]string, routes map[string][]string) (string, bool) {
+	if len(node.dependencies) == 0 {
+		return "", false
+	}
+
+	var reasons []string
+	for _, edge := range node.dependencies {
+		upstream := edge.from.agent.Name()
+		if _, ok := outputs[upstream]; !ok {
+			reasons = append(reasons, fmt.Sprintf("上游 %s 未执行", upstream))
+			continue
+		}
+		if edge.from.router != nil && !containsName(routes[upstream], node.agent.Name()) {
+			reasons = append(reasons, fmt.Sprintf("未被 %s 路由选中", upstream))
+			continue
+		}
+		if edge.condition != nil && !edge.condition(outputs) {
+			label := edge.label
+			if label == "" {
+				label = "条件"
+			}
+			reasons = append(reasons, fmt.Sprintf("%s -> %s 的%s不满足", upstream, node.agent.Name(), label))
+			continue
+		}
+		return "", false
+	}
+	return strings.Join(reasons, "; "), true
+}
+
 // executeSubsequentRound 执行后续轮次（选择最合适的Agent）
-func (d *DependencyGraph) executeSubsequentRound(ctx context.Context, input string, round int) (map[string]string, error) {
-	results := make(map[string]string)
+func (d *graphRun) executeSubsequentRound(ctx context.Context, input string, round int, skipped []string) (RoundResult, error) {
+	results := make(RoundResult)
+
+	// 第一轮被跳过的节点在后续轮次中保持跳过
+	for _, name := range skipped {
+		results[name] = &AgentResult{Status: StatusSkipped, Reason: "第一轮未被激活"}
+	}
 
 	// 获取按能力排序的节点
-	sortedNodes := d.getSortedNodesByCapability()
+	sortedNodes := make([]*Node, 0, len(d.nodes))
+	for _, node := range d.getSortedNodesByCapability() {
+		if !containsName(skipped, node.agent.Name()) {
+			sortedNodes = append(sortedNodes, node)
+		}
+	}
 
 	// 选择最合适的Agent参与讨论（这里可以根据具体需求调整选择策略）
 	selectedCount := len(sortedNodes)/2 + 1 // 至少选择一半的Agent
+	if selectedCount > len(sortedNodes) {
+		selectedCount = len(sortedNodes)
+	}
 
 	if d.maxRounds > 1 {
 		// 构建后续轮次的提示词
@@ -225,30 +565,39 @@ func (d *DependencyGraph) executeSubsequentRound(ctx context.Context, input stri
 	for i := 0; i < selectedCount; i++ {
 		node := sortedNodes[i]
 
+		// 断点恢复：跳过本轮已完成的节点
+		if prev, ok := d.progress.completed(node.agent.Name()); ok && prev.Status != StatusFailed {
+			results[node.agent.Name()] = prev
+			continue
+		}
+
 		if d.callback != nil {
 			d.callback.OnStart(node.agent.Name())
 		}
 
 		// 执行时传入完整上下文
-		result, err := node.agent.Execute(ctx, input)
+		result, err := executeWithPolicy(ctx, node.agent, input, d.policy, d.maxRetries)
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("agent %s execution failed: %w", node.agent.Name(), err)
 		}
 
 		results[node.agent.Name()] = result
+		d.progress.record(node.agent.Name(), result)
 		// 更新节点能力分数
-		d.updateNodeCapability(node, input, result)
+		if result.Status == StatusSuccess {
+			d.updateNodeCapability(node, input, result.Content)
+		}
 	}
 
 	if d.callback != nil {
-		d.callback.OnRoundComplete(round, results)
+		d.callback.OnRoundComplete(round, results.Contents())
 	}
 
 	return results, nil
 }
 
 // 辅助方法
-func (d *DependencyGraph) hasAnyDependencies() bool {
+func (d *graphRun) hasAnyDependencies() bool {
 	for _, node := range d.nodes {
 		if len(node.dependencies) > 0 {
 			return true
@@ -257,7 +606,7 @@ func (d *DependencyGraph) hasAnyDependencies() bool {
 	return false
 }
 
-func (d *DependencyGraph) getSortedNodesByCapability() []*Node {
+func (d *graphRun) getSortedNodesByCapability() []*Node {
 	nodes := make([]*Node, 0, len(d.nodes))
 	for _, node := range d.nodes {
 		nodes = append(nodes, node)
@@ -270,13 +619,23 @@ func (d *DependencyGraph) getSortedNodesByCapability() []*Node {
 	return nodes
 }
 
-func (d *DependencyGraph) updateNodeCapability(node *Node, input, output string) {
+func (d *graphRun) updateNodeCapability(node *Node, input, output string) {
 	// 这里可以实现更复杂的能力评分更新逻辑
 	relevanceScore := calculateRelevance(input, output)
 	node.capability = (node.capability + relevanceScore) / 2
+	d.graph.setCapability(node.agent.Name(), node.capability)
+}
+
+// setCapability 保存执行中更新的能力分数，供之后的执行使用
+func (d *DependencyGraph) setCapability(name string, capability float64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if node, ok := d.nodes[name]; ok {
+		node.capability = capability
+	}
 }
 
-func (d *DependencyGraph) combineRoundResults(round int, results map[string]string) string {
+func (d *graphRun) combineRoundResults(round int, results RoundResult) string {
 	var sb strings.Builder
 
 	// 添加历史讨论记录
@@ -284,7 +643,7 @@ func (d *DependencyGraph) combineRoundResults(round int, results map[string]stri
 		sb.WriteString("前面的讨论总结：\n")
 		for i := 0; i < round; i++ {
 			sb.WriteString(fmt.Sprintf("\n第 %d 轮讨论：\n", i+1))
-			for name, result := range d.roundResults[i] {
+			for name, result := range d.roundResults[i].Contents() {
 				sb.WriteString(fmt.Sprintf("[%s]: %s\n", name, result))
 			}
 		}
@@ -293,9 +652,19 @@ func (d *DependencyGraph) combineRoundResults(round int, results map[string]stri
 
 	// 添加本轮讨论结果
 	sb.WriteString("\n本轮讨论内容：\n")
-	for name, result := range results {
+	for name, result := range results.Contents() {
 		sb.WriteString(fmt.Sprintf("[%s]: %s\n", name, result))
 	}
 
 	return sb.String()
 }
+
+// containsName 判断名称列表中是否包含指定名称
+func containsName(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
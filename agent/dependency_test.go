@@ -0,0 +1,204 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// stubAgent 按调用次数返回预设输出的Agent，记录收到的输入
+type stubAgent struct {
+	name  string
+	reply func(call int, input string) string
+
+	mu     sync.Mutex
+	inputs []string
+}
+
+func newStubAgent(name string, reply func(call int, input string) string) *stubAgent {
+	return &stubAgent{name: name, reply: reply}
+}
+
+// constant 始终返回同一输出
+func constant(output string) func(int, string) string {
+	return func(int, string) string { return output }
+}
+
+func (s *stubAgent) Name() string              { return s.name }
+func (s *stubAgent) GetCapabilities() []string { return []string{s.name} }
+
+func (s *stubAgent) Execute(ctx context.Context, input string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.inputs = append(s.inputs, input)
+	return s.reply(len(s.inputs), input), nil
+}
+
+// calls 返回被调用的次数
+func (s *stubAgent) calls() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.inputs)
+}
+
+func TestGraphSkippedBranchStaysSkipped(t *testing.T) {
+	writer := newStubAgent("writer", constant("普通的文章"))
+	security := newStubAgent("security", constant("安全意见"))
+
+	g := NewDependencyGraph(3, nil)
+	g.AddAgent(writer)
+	g.AddAgent(security)
+	if err := g.AddConditionalDependency("security", "writer", "提到认证", OutputContains("writer", "认证")); err != nil {
+		t.Fatal(err)
+	}
+
+	rounds, err := g.ExecuteDetailed(context.Background(), "主题")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rounds) != 3 {
+		t.Fatalf("got %d rounds, want 3", len(rounds))
+	}
+	for i, round := range rounds {
+		if got := round["security"]; got == nil || got.Status != StatusSkipped {
+			t.Errorf("round %d: security = %+v, want skipped", i+1, got)
+		}
+		if got := round["writer"]; got == nil || got.Status != StatusSuccess {
+			t.Errorf("round %d: writer = %+v, want success", i+1, got)
+		}
+	}
+	if reason := rounds[0]["security"].Reason; !strings.Contains(reason, "提到认证") {
+		t.Errorf("skip reason = %q, want the edge label", reason)
+	}
+	if security.calls() != 0 {
+		t.Errorf("skipped agent executed %d times", security.calls())
+	}
+}
+
+func TestGraphRouterChoosesTarget(t *testing.T) {
+	triage := newStubAgent("triage", constant("这是一个缺陷"))
+	bug := newStubAgent("bug", constant("已修复"))
+	feature := newStubAgent("feature", constant("已排期"))
+
+	g := NewDependencyGraph(1, nil)
+	for _, a := range []Agent{triage, bug, feature} {
+		g.AddAgent(a)
+	}
+	for _, name := range []string{"bug", "feature"} {
+		if err := g.AddDependency(name, "triage"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := g.SetRouter("triage", func(output string) []string {
+		if strings.Contains(output, "缺陷") {
+			return []string{"bug"}
+		}
+		return []string{"feature"}
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	rounds, err := g.ExecuteDetailed(context.Background(), "问题描述")
+	if err != nil {
+		t.Fatal(err)
+	}
+	round := rounds[0]
+	if got := round["bug"]; got.Status != StatusSuccess || got.Content != "已修复" {
+		t.Errorf("bug = %+v, want success", got)
+	}
+	if got := round["feature"]; got.Status != StatusSkipped || !strings.Contains(got.Reason, "未被 triage 路由选中") {
+		t.Errorf("feature = %+v, want skipped by router", got)
+	}
+	if feature.calls() != 0 {
+		t.Errorf("unrouted agent executed %d times", feature.calls())
+	}
+}
+
+// newReviewLoop 创建 writer -> security(条件) -> reviewer 的依赖图，reviewer 未通过时回到 writer
+func newReviewLoop(t *testing.T, writer, security, reviewer Agent, maxIterations int) *DependencyGraph {
+	t.Helper()
+	g := NewDependencyGraph(1, nil)
+	for _, a := range []Agent{writer, security, reviewer} {
+		g.AddAgent(a)
+	}
+	must := func(err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	must(g.AddConditionalDependency("security", "writer", "提到认证", OutputContains("writer", "认证")))
+	must(g.AddDependency("reviewer", "writer"))
+	must(g.AddDependency("reviewer", "security"))
+	must(g.AddLoop("reviewer", "writer", "未通过审核", maxIterations, OutputContains("reviewer", "APPROVED")))
+	return g
+}
+
+func TestGraphLoopStopsAtMaxIterations(t *testing.T) {
+	writer := newStubAgent("writer", constant("草稿"))
+	security := newStubAgent("security", constant("安全意见"))
+	reviewer := newStubAgent("reviewer", constant("REJECTED"))
+
+	rounds, err := newReviewLoop(t, writer, security, reviewer, 3).ExecuteDetailed(context.Background(), "主题")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if writer.calls() != 3 || reviewer.calls() != 3 {
+		t.Errorf("writer ran %d times, reviewer %d times, want 3", writer.calls(), reviewer.calls())
+	}
+	for _, name := range []string{"writer", "reviewer"} {
+		if got := rounds[0][name]; got.Status != StatusSuccess || got.Iterations != 3 {
+			t.Errorf("%s = %+v, want 3 iterations", name, got)
+		}
+	}
+}
+
+func TestGraphLoopStopsWhenConditionHolds(t *testing.T) {
+	writer := newStubAgent("writer", constant("草稿"))
+	security := newStubAgent("security", constant("安全意见"))
+	reviewer := newStubAgent("reviewer", func(call int, input string) string {
+		if call == 2 {
+			return "APPROVED"
+		}
+		return "REJECTED"
+	})
+
+	rounds, err := newReviewLoop(t, writer, security, reviewer, 5).ExecuteDetailed(context.Background(), "主题")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if writer.calls() != 2 || reviewer.calls() != 2 {
+		t.Errorf("writer ran %d times, reviewer %d times, want 2", writer.calls(), reviewer.calls())
+	}
+	if got := rounds[0]["reviewer"]; got.Content != "APPROVED" || got.Iterations != 2 {
+		t.Errorf("reviewer = %+v, want APPROVED after 2 iterations", got)
+	}
+}
+
+func TestGraphLoopSkippedBodyKeepsLastResult(t *testing.T) {
+	// 只有第一稿提到认证，之后的迭代中 security 不再被激活
+	writer := newStubAgent("writer", func(call int, input string) string {
+		if call == 1 {
+			return "第一稿，使用认证"
+		}
+		return "修改稿"
+	})
+	security := newStubAgent("security", constant("第一次安全意见"))
+	reviewer := newStubAgent("reviewer", constant("REJECTED"))
+
+	rounds, err := newReviewLoop(t, writer, security, reviewer, 3).ExecuteDetailed(context.Background(), "主题")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if security.calls() != 1 {
+		t.Errorf("security ran %d times, want 1", security.calls())
+	}
+	got := rounds[0]["security"]
+	if got.Status != StatusSuccess || got.Content != "第一次安全意见" || got.Iterations != 1 {
+		t.Errorf("security = %+v, want the result of the first iteration", got)
+	}
+	if got := rounds[0]["reviewer"]; got.Iterations != 3 {
+		t.Errorf("reviewer ran %d iterations, want 3", got.Iterations)
+	}
+}
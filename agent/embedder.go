@@ -0,0 +1,138 @@
+package agent
+
+import (
+	"context"
+	"hash/fnv"
+	"math"
+	"multi-agent/oneapi"
+	"strings"
+	"unicode"
+)
+
+// Embedder 将文本转换为向量
+type Embedder interface {
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// APIEmbedder 使用 /v1/embeddings 接口的向量化
+type APIEmbedder struct {
+	model  string
+	client *oneapi.Client
+}
+
+// NewAPIEmbedder 创建调用向量化接口的Embedder，如 text-embedding-3-small
+func NewAPIEmbedder(model string) *APIEmbedder {
+	return &APIEmbedder{model: model, client: oneapi.NewClient()}
+}
+
+// SetClient 设置使用的客户端
+func (e *APIEmbedder) SetClient(client *oneapi.Client) {
+	e.client = client
+}
+
+// Embed 实现 Embedder
+func (e *APIEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	return e.client.Embeddings(ctx, e.model, texts)
+}
+
+// HashEmbedder 基于特征哈希的本地向量化，无需调用模型
+//
+// 英文按单词、中文按相邻两字切分后哈希到固定维度，适合离线使用和测试，
+// 只能匹配字面相近的内容，语义召回效果不如 APIEmbedder。
+type HashEmbedder struct {
+	dim int
+}
+
+// NewHashEmbedder 创建本地向量化，dim 为向量维度，默认512
+func NewHashEmbedder(dim int) *HashEmbedder {
+	if dim <= 0 {
+		dim = 512
+	}
+	return &HashEmbedder{dim: dim}
+}
+
+// Embed 实现 Embedder
+func (e *HashEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vector := make([]float32, e.dim)
+		for _, feature := range hashFeatures(text) {
+			h := fnv.New32a()
+			h.Write([]byte(feature))
+			sum := h.Sum32()
+			// 最高位决定符号，减少哈希冲突带来的偏差
+			if sum&(1<<31) != 0 {
+				vector[int(sum%uint32(e.dim))]--
+			} else {
+				vector[int(sum%uint32(e.dim))]++
+			}
+		}
+		normalize(vector)
+		vectors[i] = vector
+	}
+	return vectors, nil
+}
+
+// hashFeatures 切分文本：字母数字按单词，汉字等按单字和相邻两字
+func hashFeatures(text string) []string {
+	var features []string
+	var word strings.Builder
+	var prev rune
+	flush := func() {
+		if word.Len() > 0 {
+			features = append(features, word.String())
+			word.Reset()
+		}
+	}
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul):
+			flush()
+			features = append(features, string(r))
+			if prev != 0 {
+				features = append(features, string([]rune{prev, r}))
+			}
+			prev = r
+			continue
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			word.WriteRune(r)
+		default:
+			flush()
+		}
+		prev = 0
+	}
+	flush()
+	return features
+}
+
+// normalize 将向量归一化为单位长度
+func normalize(v []float32) {
+	var sum float64
+	for _, x := range v {
+		sum += float64(x) * float64(x)
+	}
+	if sum == 0 {
+		return
+	}
+	norm := float32(math.Sqrt(sum))
+	for i := range v {
+		v[i] /= norm
+	}
+}
+
+// cosine 计算余弦相似度，维度不同时返回0
+func cosine(a, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
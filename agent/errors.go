@@ -10,4 +10,11 @@ var (
   ErrExecutionFailed   = errors.New("agent execution failed")
   ErrToolNotFound      = errors.New("tool not found")
   ErrInvalidParameters = errors.New("invalid parameters")
+  ErrCheckpointNotFound = errors.New("checkpoint not found")
+  ErrVersionConflict   = errors.New("version conflict")
+  ErrTypeMismatch      = errors.New("type mismatch")
+  ErrUnsupportedTranscript = errors.New("unsupported transcript version")
+  ErrToolLimitExceeded = errors.New("tool call limit exceeded")
+  ErrToolCallFailed    = errors.New("tool call failed")
+  ErrHumanTimeout      = errors.New("human input timeout")
 )
@@ -3,28 +3,46 @@ package agent
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"multi-agent/oneapi"
 	"multi-agent/tools"
+	"sort"
 	"strings"
 	"sync"
-	"time"
 )
 
 // ExpertAgent 专家型Agent
 type ExpertAgent struct {
 	*BaseAgent
-	expertise   string                // 专业领域
-	description string                // 专家描述
-	useStream   bool                  // 是否使用流式输出
-	callback    OutputCallback        // 回调函数
-	tools       map[string]tools.Tool // 添加工具映射
-	mu          sync.Mutex            // 添加互斥锁来保护通道操作
-	Model       string                // 模型名称
-	selector    AgentSelector         // 添加选择器
+	expertise    string                // 专业领域
+	description  string                // 专家描述
+	useStream    bool                  // 是否使用流式输出
+	callback     OutputCallback        // 回调函数
+	tools        map[string]tools.Tool // 添加工具映射
+	mu           sync.Mutex            // 添加互斥锁来保护通道操作
+	Model        string                // 模型名称，执行期间修改请使用 SetModel
+	selector     AgentSelector         // 添加选择器
+	usage        oneapi.Usage          // 最近一次完成的Execute的Token用量
+	contextLimit int                   // 上下文长度，0表示按模型自动确定
+	trimStrategy TrimStrategy          // 超出上下文时的裁剪策略
+	memStrategy  MemoryStrategy        // 记忆整理策略，未设置时使用会话的策略
+	longTerm     *LongTermMemory       // 长期记忆
+	recallFilter RecallFilter          // 召回长期记忆的过滤条件
+	toolLimits   toolLimits            // 工具调用轮数和重复调用的限制
+
+	// 覆盖工具的执行限制，键为工具名称，空字符串表示所有工具
+	resources map[string]tools.Limits
+	// 工具调用的审批策略
+	policy ToolPolicy
 }
 
+const (
+	maxCompletionTokens = 1024 // 每次请求预留的回复Token数
+	maxContextRetries   = 3    // 上下文超长时裁剪重试的次数
+)
+
 // NewExpertAgent 创建新的专家Agent
 func NewSelectorAgent(name string, expertise string, description string) *ExpertAgent {
 	return newDefaultAgent(name, expertise, description, "", NewDefaultSelector())
@@ -45,39 +63,159 @@ func newDefaultAgent(name string, expertise string, description string, model st
 		log.Fatal("创建AI客户端失败")
 	}
 	return &ExpertAgent{
-		BaseAgent:   NewBaseAgent(name, capabilities, client, globalMemoryManager, getOrCreateTaskID()),
+		BaseAgent:   NewBaseAgent(name, capabilities, client, nil, ""),
 		expertise:   expertise,
 		description: description,
 		useStream:   false,
 		tools:       make(map[string]tools.Tool),
 		Model:       model,
 		selector:    selector,
+		toolLimits: toolLimits{
+			maxRounds:   DefaultMaxToolRounds,
+			maxRepeats:  DefaultMaxRepeatedCalls,
+			maxFailures: DefaultMaxToolFailures,
+			concurrency: DefaultToolConcurrency,
+		},
 	}
 }
 
-// 全局的MemoryManager实例
-var globalMemoryManager = NewMemoryManager()
+// ResetTaskID 重置任务ID
+//
+// Deprecated: 记忆已按会话隔离（见 Session），此函数不再有作用。
+func ResetTaskID() {}
 
-// 全局任务ID
-var currentTaskID string
-var taskMutex sync.Mutex
+// recorderFor 返回上下文中会话的运行记录器，没有时返回nil
+func (e *ExpertAgent) recorderFor(ctx context.Context, systemPrompt, model string, toolList []tools.Tool) *Recorder {
+	s := SessionFromContext(ctx)
+	if s == nil || s.Recorder() == nil {
+		return nil
+	}
+	var toolNames []string
+	for _, tool := range toolList {
+		toolNames = append(toolNames, tool.GetName())
+	}
+	s.Recorder().agent(TranscriptAgent{
+		Name:         e.Name(),
+		Expertise:    e.expertise,
+		Model:        model,
+		SystemPrompt: systemPrompt,
+		Tools:        toolNames,
+	})
+	return s.Recorder()
+}
 
-// 获取或创建任务ID
-func getOrCreateTaskID() string {
-	taskMutex.Lock()
-	defer taskMutex.Unlock()
+// SetToolPolicy 设置工具调用的审批策略，需要批准的工具在执行前交由审批者决定，
+// 被拒绝时以工具结果告知模型
+func (e *ExpertAgent) SetToolPolicy(policy ToolPolicy) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.policy = policy
+}
 
-	if currentTaskID == "" {
-		currentTaskID = fmt.Sprintf("task_%d", time.Now().UnixNano())
+// SetToolConcurrency 设置模型一次返回多个工具调用时最多同时执行的数量，1 表示依次执行，
+// 小于等于0时使用默认值
+func (e *ExpertAgent) SetToolConcurrency(n int) {
+	if n <= 0 {
+		n = DefaultToolConcurrency
 	}
-	return currentTaskID
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.toolLimits.concurrency = n
 }
 
-// ResetTaskID 重置任务ID（在新任务开始时调用）
-func ResetTaskID() {
-	taskMutex.Lock()
-	defer taskMutex.Unlock()
-	currentTaskID = ""
+// SetMaxToolFailures 设置连续失败多少次工具调用后终止执行，小于等于0时使用默认值。
+// 未达到该次数时，失败原因作为工具结果返回给模型
+func (e *ExpertAgent) SetMaxToolFailures(n int) {
+	if n <= 0 {
+		n = DefaultMaxToolFailures
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.toolLimits.maxFailures = n
+}
+
+// SetToolLimits 设置一次执行中最多的工具调用轮数、相同调用最多执行的次数，以及超出时的处理方式。
+// 小于等于0的值使用默认值
+func (e *ExpertAgent) SetToolLimits(maxRounds, maxRepeats int, action ToolLimitAction) {
+	if maxRounds <= 0 {
+		maxRounds = DefaultMaxToolRounds
+	}
+	if maxRepeats <= 0 {
+		maxRepeats = DefaultMaxRepeatedCalls
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.toolLimits.maxRounds, e.toolLimits.maxRepeats, e.toolLimits.action = maxRounds, maxRepeats, action
+}
+
+// SetMemoryStrategy 设置记忆整理策略，优先于会话的策略
+func (e *ExpertAgent) SetMemoryStrategy(strategy MemoryStrategy) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.memStrategy = strategy
+}
+
+// compactMemory 执行结束后按策略整理记忆，整理失败不影响本次结果
+func (e *ExpertAgent) compactMemory(ctx context.Context, memory *Memory) {
+	e.mu.Lock()
+	strategy := e.memStrategy
+	e.mu.Unlock()
+	if s := SessionFromContext(ctx); strategy == nil && s != nil {
+		strategy = s.MemoryStrategy()
+	}
+	if strategy == nil {
+		return
+	}
+	if err := memory.Compact(ctx, strategy); err != nil {
+		log.Printf("整理 %s 的记忆失败: %v", e.Name(), err)
+	}
+}
+
+// SetLongTermMemory 设置长期记忆，filter 限定召回的范围，如只召回本Agent或最近一段时间的记忆
+func (e *ExpertAgent) SetLongTermMemory(ltm *LongTermMemory, filter RecallFilter) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.longTerm = ltm
+	e.recallFilter = filter
+}
+
+// longTermSettings 返回长期记忆设置
+func (e *ExpertAgent) longTermSettings() (*LongTermMemory, RecallFilter) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.longTerm, e.recallFilter
+}
+
+// recall 召回与输入相关的长期记忆，没有时返回空字符串，召回失败不影响执行
+func (e *ExpertAgent) recall(ctx context.Context, input string) string {
+	ltm, filter := e.longTermSettings()
+	if ltm == nil {
+		return ""
+	}
+	items, err := ltm.Recall(ctx, input, filter)
+	if err != nil {
+		log.Printf("召回 %s 的长期记忆失败: %v", e.Name(), err)
+		return ""
+	}
+	if len(items) == 0 {
+		return ""
+	}
+	return formatRecalled(items)
+}
+
+// rememberLongTerm 将本次问答写入长期记忆，写入失败不影响本次结果
+func (e *ExpertAgent) rememberLongTerm(ctx context.Context, input, output string) {
+	ltm, _ := e.longTermSettings()
+	if ltm == nil || output == "" {
+		return
+	}
+	task := ""
+	if s := SessionFromContext(ctx); s != nil {
+		task = s.ID()
+	}
+	if err := ltm.rememberExchange(ctx, e.Name(), task, input, output); err != nil {
+		log.Printf("保存 %s 的长期记忆失败: %v", e.Name(), err)
+	}
 }
 
 // SetStreamOutput 设置是否使用流式输出
@@ -101,117 +239,194 @@ func (e *ExpertAgent) AddTool(tool tools.Tool) {
 	e.tools[tool.GetName()] = tool
 }
 
+// Expertise 返回专业领域
+func (e *ExpertAgent) Expertise() string {
+	return e.expertise
+}
+
+// Description 返回专家描述
+func (e *ExpertAgent) Description() string {
+	return e.description
+}
+
+// Tools 返回Agent可用的工具，按名称排序
+func (e *ExpertAgent) Tools() []tools.Tool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	list := make([]tools.Tool, 0, len(e.tools))
+	for _, tool := range e.tools {
+		list = append(list, tool)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].GetName() < list[j].GetName()
+	})
+	return list
+}
+
 // Execute 执行专家分析
 func (e *ExpertAgent) Execute(ctx context.Context, input string) (string, error) {
+	output, _, err := e.ExecuteWithUsage(ctx, input)
+	return output, err
+}
+
+// ExecuteWithUsage 执行专家分析，同时返回本次执行累计的Token用量（失败时为已消耗的部分）。
+// 同一个Agent在多个会话中并发执行时，应使用它而不是 LastUsage 获取用量
+func (e *ExpertAgent) ExecuteWithUsage(ctx context.Context, input string) (string, oneapi.Usage, error) {
+	var usage oneapi.Usage
+	defer func() { e.setUsage(usage) }()
+
+	// 执行期间使用同一份设置，避免与 SetCallback、SetStreamOutput、AddTool、SetModel 并发冲突
+	useStream, callback := e.outputSettings()
+	callback = callbackFor(ctx, callback)
+	toolList, model := e.toolSettings()
 	defer func() {
 		// 确保在函数返回前调用OnComplete
-		if !e.useStream && e.callback != nil {
-			e.callback.OnComplete(e.Name())
+		if !useStream && callback != nil {
+			callback.OnComplete(e.Name())
 		}
 	}()
-	if e.callback != nil {
-		e.callback.OnStart(e.Name())
+	if callback != nil {
+		callback.OnStart(e.Name())
 	}
 
+	memory := e.memoryFor(ctx)
+	e.syncShared(ctx, memory)
+
 	// 构建系统提示词
-	systemPrompt := e.buildSystemPrompt()
+	systemPrompt := e.buildSystemPrompt(toolList)
+	recorder := e.recorderFor(ctx, systemPrompt, model, toolList)
 
 	messages := []oneapi.ChatMessage{
 		{Role: "system", Content: systemPrompt},
 	}
+	// 召回相关的长期记忆，作为系统消息放在历史之前
+	if recalled := e.recall(ctx, input); recalled != "" {
+		messages = append(messages, oneapi.ChatMessage{Role: "system", Content: recalled})
+		recorder.message(e.Name(), messages[len(messages)-1])
+	}
 	// 添加历史记录
-	messages = append(messages, e.memory.GetHistory()...)
+	messages = append(messages, memory.GetHistory()...)
 	// 添加当前输入
 	messages = append(messages, oneapi.ChatMessage{
 		Role:    "user",
 		Content: input,
 	})
 	var finalResponse strings.Builder
+	toolDefs := buildToolDefs(toolList)
+	budget, strategy := e.contextSettings(toolDefs, model)
+	contextRetries := 0
+	e.mu.Lock()
+	guard := newToolGuard(e.toolLimits)
+	e.mu.Unlock()
+	forceAnswer := false // 已超出工具调用限制，要求模型直接回答
+	failures := 0        // 连续失败的工具调用次数
 	// 保存历史会话
-	e.memory.AddMessage(oneapi.ChatMessage{
+	memory.AddMessage(oneapi.ChatMessage{
 		Role:    "user",
 		Content: input,
 	})
+	recorder.message(e.Name(), oneapi.ChatMessage{Role: "user", Content: input})
 
 	for {
 		req := oneapi.ChatCompletionRequest{
-			Messages:  messages,
-			Stream:    e.useStream,
-			Tools:     e.buildToolDefs(), // 添加工具定义
-			MaxTokens: 1024,
-			Model:     e.Model,
+			Messages:  strategy.Trim(messages, budget), // 保留系统提示词和最新的对话
+			Stream:    useStream,
+			Tools:     toolDefs, // 添加工具定义
+			MaxTokens: maxCompletionTokens,
+			Model:     model,
+		}
+		if forceAnswer {
+			req.ToolChoice = "none"
 		}
 		// 创建本地回调函数，确保其在范围内访问callback
 		var streamCallback func(string)
-		if e.useStream && e.callback != nil {
+		if useStream && callback != nil {
 			// 预先通知开始
-			e.callback.OnStart(e.Name())
+			callback.OnStart(e.Name())
 			streamCallback = func(content string) {
-				e.callback.OnContent(e.Name(), content)
+				callback.OnContent(e.Name(), content)
 			}
 		}
 
 		resp, err := e.client.ChatCompletion(ctx, req, streamCallback)
+		if errors.Is(err, oneapi.ErrContextLengthExceeded) && contextRetries < maxContextRetries {
+			// 估算偏低导致超长，按本次请求的大小缩小预算后重试
+			contextRetries++
+			budget = oneapi.CountMessagesTokens(req.Messages) * 3 / 4
+			continue
+		}
 		if err != nil {
-			return "", fmt.Errorf("专家分析失败: %w", err)
+			err = fmt.Errorf("专家分析失败: %w", err)
+			recorder.fail(e.Name(), err)
+			return "", usage, err
 		}
+		usage.Add(resp.Usage)
 		message := resp.Choices[0].Message
 		// 处理工具调用
 		if len(message.ToolCalls) > 0 {
-			if e.useStream && e.callback != nil {
-				e.callback.OnContent(e.Name(), "\n\n正在调用工具...\n")
-			}
-
-			// 处理每个工具调用
-			for _, toolCall := range message.ToolCalls {
-				// 验证工具调用
-				if toolCall.Function.Name == "" || toolCall.Function.Arguments == "" {
-					continue
+			if reason := guard.check(message.ToolCalls); reason != "" {
+				// 模型在 tool_choice: none 下仍调用工具时也只能返回错误
+				if guard.limits.action == FailOnToolLimit || forceAnswer {
+					err := fmt.Errorf("%w: %s", ErrToolLimitExceeded, reason)
+					recorder.fail(e.Name(), err)
+					return "", usage, err
 				}
-
-				// 验证参数
-				var params map[string]interface{}
-				if err := json.Unmarshal([]byte(toolCall.Function.Arguments), &params); err != nil {
-					continue
+				forceAnswer = true
+				notice := oneapi.ChatMessage{
+					Role:    "system",
+					Content: fmt.Sprintf("工具调用已停止（%s），请根据已有信息直接给出最终回答，不要再调用工具。", reason),
 				}
+				messages = append(messages, notice)
+				recorder.message(e.Name(), notice)
+				continue
+			}
+			if useStream && callback != nil {
+				callback.OnContent(e.Name(), "\n\n正在调用工具...\n")
+			}
+
+			// 并发执行本轮的工具调用，失败或参数无效的调用也以 tool 消息回复，由模型决定如何修正
+			call := oneapi.ChatMessage{Role: "assistant", Content: message.Content, ToolCalls: message.ToolCalls}
+			recorder.message(e.Name(), call)
+			outcomes := e.runToolCalls(ctx, message.ToolCalls, guard.limits.concurrency)
+			if err := ctx.Err(); err != nil {
+				// 运行被取消，不再把取消导致的失败返回给模型
+				recorder.fail(e.Name(), err)
+				return "", usage, err
+			}
 
-				// 执行工具调用
-				toolResult, err := e.executeToolCall(ctx, toolCall)
-				if err != nil {
-					if e.useStream && e.callback != nil {
-						e.callback.OnContent(e.Name(), fmt.Sprintf("\n工具调用失败: %v\n", err))
+			// 一条包含全部调用的 assistant 消息，其后按调用顺序跟随对应的 tool 消息
+			messages = append(messages, call)
+			for i, toolCall := range message.ToolCalls {
+				toolResult, err := outcomes[i].result, outcomes[i].err
+				resultStr := fmt.Sprintf("\n工具 %s 执行结果：\n%s\n", toolCall.Function.Name, toolResult)
+				var te *toolCallError
+				if errors.As(err, &te) && te.Kind == ToolErrorDenied {
+					// 被拒绝不是工具或参数的问题，不计入连续失败次数
+					toolResult = toolErrorResult(toolCall, err)
+					resultStr = fmt.Sprintf("\n工具 %s %v\n", toolCall.Function.Name, err)
+				} else if err != nil {
+					failures++
+					if failures >= guard.limits.maxFailures {
+						if useStream && callback != nil {
+							callback.OnContent(e.Name(), fmt.Sprintf("\n工具调用失败: %v\n", err))
+						}
+						err = fmt.Errorf("%w: 连续 %d 次失败: %w", ErrToolCallFailed, failures, err)
+						recorder.fail(e.Name(), err)
+						return "", usage, err
 					}
-					return "", fmt.Errorf("工具调用失败: %w", err)
+					toolResult = toolErrorResult(toolCall, err)
+					resultStr = fmt.Sprintf("\n工具 %s 调用失败：%v\n", toolCall.Function.Name, err)
+				} else {
+					failures = 0
 				}
+				result := oneapi.ChatMessage{Role: "tool", ToolCallID: toolCall.ID, Content: toolResult}
+				messages = append(messages, result)
+				recorder.message(e.Name(), result)
 
-				// 添加到消息历史
-				messages = append(messages, oneapi.ChatMessage{
-					Role:      "assistant",
-					ToolCalls: []oneapi.ToolCall{toolCall},
-				})
-				messages = append(messages, oneapi.ChatMessage{
-					Role:       "tool",
-					ToolCallID: toolCall.ID,
-					Content:    toolResult,
-				})
-
-				resultStr := fmt.Sprintf("\n工具 %s 执行结果：\n%s\n",
-					toolCall.Function.Name, toolResult)
-
-				if e.useStream && e.callback != nil {
-					e.callback.OnContent(e.Name(), resultStr)
+				if useStream && callback != nil {
+					callback.OnContent(e.Name(), resultStr)
 				}
-
 				finalResponse.WriteString(resultStr)
-
-				// 存储对话历史
-				e.memory.AddMessage(oneapi.ChatMessage{
-					Role:    "assistant",
-					Content: finalResponse.String(),
-				})
-
-				// 添加短暂延迟，确保输出顺序
-				time.Sleep(100 * time.Millisecond)
 			}
 
 			// 继续对话以处理工具结果
@@ -220,43 +435,77 @@ func (e *ExpertAgent) Execute(ctx context.Context, input string) (string, error)
 
 		// 处理正常响应
 		if resp.Choices[0].Message.Content != "" {
-			if !e.useStream && e.callback != nil {
-				e.callback.OnContent(e.Name(), resp.Choices[0].Message.Content)
+			if !useStream && callback != nil {
+				callback.OnContent(e.Name(), resp.Choices[0].Message.Content)
 			}
 			finalResponse.WriteString(resp.Choices[0].Message.Content)
-			// 存储对话历史
-			e.memory.AddMessage(oneapi.ChatMessage{
-				Role:    "assistant",
-				Content: finalResponse.String(),
-			})
+			recorder.message(e.Name(), oneapi.ChatMessage{Role: "assistant", Content: resp.Choices[0].Message.Content})
 		}
 		break
 	}
 
+	// 存储对话历史，工具结果和最终回复合为一条
+	if finalResponse.Len() > 0 {
+		memory.AddMessage(oneapi.ChatMessage{
+			Role:    "assistant",
+			Content: finalResponse.String(),
+		})
+	}
+
 	// 如果是流式输出，在这里调用完成回调
-	if e.useStream && e.callback != nil {
-		e.callback.OnComplete(e.Name())
+	if useStream && callback != nil {
+		callback.OnComplete(e.Name())
 	}
+	e.postShared(ctx, finalResponse.String())
+	e.compactMemory(ctx, memory)
+	e.rememberLongTerm(ctx, input, finalResponse.String())
 
-	return finalResponse.String(), nil
+	return finalResponse.String(), usage, nil
 }
 
 func (e *ExpertAgent) executeToolCall(ctx context.Context, toolCall oneapi.ToolCall) (string, error) {
-	tool, exists := e.tools[toolCall.Function.Name]
+	name := toolCall.Function.Name
+	e.mu.Lock()
+	tool, exists := e.tools[name]
+	e.mu.Unlock()
 	if !exists {
-		return "", fmt.Errorf("未找到工具: %s", toolCall.Function.Name)
+		return "", &toolCallError{Kind: ToolErrorNotFound, Tool: name, Err: fmt.Errorf("未找到工具: %s", name)}
 	}
 
-	// 解析参数
-	var params map[string]interface{}
-	if err := json.Unmarshal([]byte(toolCall.Function.Arguments), &params); err != nil {
-		return "", fmt.Errorf("参数解析失败: %w", err)
+	// 解析参数，没有参数的工具可能收到空字符串
+	params := make(map[string]interface{})
+	if strings.TrimSpace(toolCall.Function.Arguments) != "" {
+		if err := json.Unmarshal([]byte(toolCall.Function.Arguments), &params); err != nil {
+			return "", &toolCallError{Kind: ToolErrorInvalidArgument, Tool: name, Err: fmt.Errorf("参数解析失败: %w", err)}
+		}
+	}
+	var missing []string
+	for _, p := range getRequiredParams(tool.GetParameters()) {
+		if _, ok := params[p]; !ok {
+			missing = append(missing, p)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return "", &toolCallError{Kind: ToolErrorInvalidArgument, Tool: name, Err: fmt.Errorf("缺少必需参数: %s", strings.Join(missing, ", "))}
 	}
 
 	// 执行工具
-	result, err := tool.Execute(ctx, params)
+	e.mu.Lock()
+	policy := e.policy
+	e.mu.Unlock()
+	if err := policy.approve(ctx, e.Name(), toolCall.ID, name, params); err != nil {
+		return "", err
+	}
+
+	limits := e.resourceLimitsFor(tool)
+	result, err := executeWithTimeout(withAgentName(ctx, e.Name()), tool, params, limits.Timeout)
 	if err != nil {
-		return "", fmt.Errorf("工具执行失败: %w", err)
+		var te *toolCallError
+		if errors.As(err, &te) {
+			return "", err
+		}
+		return "", &toolCallError{Kind: ToolErrorExecution, Tool: name, Err: err}
 	}
 
 	// 将结果转换为字符串
@@ -270,11 +519,75 @@ func (e *ExpertAgent) executeToolCall(ctx context.Context, toolCall oneapi.ToolC
 	default:
 		resultBytes, err := json.MarshalIndent(result, "", "  ")
 		if err != nil {
-			return "", fmt.Errorf("结果格式化失败: %w", err)
+			return "", &toolCallError{Kind: ToolErrorExecution, Tool: name, Err: fmt.Errorf("结果格式化失败: %w", err)}
 		}
 		resultStr = string(resultBytes)
 	}
-	return resultStr, nil
+	return truncateResult(resultStr, limits.MaxResultSize), nil
+}
+
+// LastUsage 返回最近一次完成的Execute累计的Token用量。
+// 并发执行时无法区分属于哪一次执行，请使用 ExecuteWithUsage
+func (e *ExpertAgent) LastUsage() oneapi.Usage {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.usage
+}
+
+func (e *ExpertAgent) setUsage(usage oneapi.Usage) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.usage = usage
+}
+
+// SetModel 设置使用的模型，空字符串表示配置中的默认模型
+func (e *ExpertAgent) SetModel(model string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.Model = model
+}
+
+// toolSettings 返回当前的工具（按名称排序）和模型
+func (e *ExpertAgent) toolSettings() ([]tools.Tool, string) {
+	e.mu.Lock()
+	model := e.Model
+	e.mu.Unlock()
+	return e.Tools(), model
+}
+
+// SetContextLimit 设置上下文长度（Token），0表示按模型自动确定
+func (e *ExpertAgent) SetContextLimit(limit int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.contextLimit = limit
+}
+
+// SetTrimStrategy 设置超出上下文时的裁剪策略，默认为 DropMiddleStrategy
+func (e *ExpertAgent) SetTrimStrategy(strategy TrimStrategy) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.trimStrategy = strategy
+}
+
+// contextSettings 返回消息可用的Token预算和裁剪策略，预算扣除了回复和工具定义占用的部分
+func (e *ExpertAgent) contextSettings(toolDefs []oneapi.ToolDef, model string) (int, TrimStrategy) {
+	e.mu.Lock()
+	limit, strategy := e.contextLimit, e.trimStrategy
+	e.mu.Unlock()
+	if limit <= 0 {
+		limit = e.client.ContextLimit(model)
+	}
+	if strategy == nil {
+		strategy = DropMiddleStrategy{}
+	}
+	return limit - maxCompletionTokens - countToolDefTokens(toolDefs), strategy
+}
+
+// outputSettings 返回当前的流式输出设置和回调
+func (e *ExpertAgent) outputSettings() (bool, OutputCallback) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.useStream, e.callback
 }
 
 // SetCallback 设置输出回调
@@ -285,9 +598,9 @@ func (e *ExpertAgent) SetCallback(callback OutputCallback) {
 }
 
 // buildToolDefs 构建工具定义
-func (e *ExpertAgent) buildToolDefs() []oneapi.ToolDef {
+func buildToolDefs(toolList []tools.Tool) []oneapi.ToolDef {
 	var defs []oneapi.ToolDef
-	for _, tool := range e.tools {
+	for _, tool := range toolList {
 		params := make(map[string]oneapi.Property)
 		for name, spec := range tool.GetParameters() {
 			params[name] = oneapi.Property{
@@ -313,36 +626,6 @@ func (e *ExpertAgent) buildToolDefs() []oneapi.ToolDef {
 	return defs
 }
 
-// handleToolCalls 处理工具调用
-func (e *ExpertAgent) handleToolCalls(ctx context.Context, toolCalls []oneapi.ToolCall) (string, error) {
-	var results []string
-
-	for _, call := range toolCalls {
-		tool, exists := e.tools[call.Function.Name]
-		if !exists {
-			return "", fmt.Errorf("tool not found: %s", call.Function.Name)
-		}
-
-		// 解析参数
-		var params map[string]interface{}
-		if err := json.Unmarshal([]byte(call.Function.Arguments), &params); err != nil {
-			return "", fmt.Errorf("invalid tool arguments: %w", err)
-		}
-
-		// 执行工具调用
-		result, err := tool.Execute(ctx, params)
-		if err != nil {
-			return "", fmt.Errorf("tool execution failed: %w", err)
-		}
-
-		// 转换结果为字符串
-		resultStr := fmt.Sprintf("工具 %s 的执行结果：\n%v", call.Function.Name, result)
-		results = append(results, resultStr)
-	}
-
-	return strings.Join(results, "\n\n"), nil
-}
-
 // getRequiredParams 获取必需参数列表
 func getRequiredParams(params map[string]tools.ParameterSpec) []string {
 	var required []string
@@ -354,7 +637,7 @@ func getRequiredParams(params map[string]tools.ParameterSpec) []string {
 	return required
 }
 
-func (e *ExpertAgent) buildSystemPrompt() string {
+func (e *ExpertAgent) buildSystemPrompt(toolList []tools.Tool) string {
 	var prompt strings.Builder
 	prompt.WriteString(fmt.Sprintf(`你是一位%s领域的专家。%s。
 
@@ -372,7 +655,7 @@ func (e *ExpertAgent) buildSystemPrompt() string {
 	))
 	prompt.WriteString("你可以使用以下工具来完成任务。使用工具时，请严格按照以下格式提供参数：\n\n")
 
-	for _, tool := range e.tools {
+	for _, tool := range toolList {
 		prompt.WriteString(fmt.Sprintf("工具名称：%s\n", tool.GetName()))
 		prompt.WriteString(fmt.Sprintf("描述：%s\n", tool.GetDescription()))
 		prompt.WriteString("参数：\n")
@@ -0,0 +1,293 @@
+package agent
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// diagramNode 导出图中的节点
+type diagramNode struct {
+	id     string
+	lines  []string     // 标签内容，每项一行
+	status ResultStatus // 标注的执行状态，为空表示未标注
+}
+
+// diagramEdge 导出图中的边
+type diagramEdge struct {
+	from   string
+	to     string
+	label  string
+	dashed bool // 条件边、路由边和循环边使用虚线
+}
+
+// runSummary 一次运行中单个Agent的汇总
+type runSummary struct {
+	status   ResultStatus
+	runs     int
+	duration time.Duration
+	tokens   int
+}
+
+// summarizeRun 汇总每个Agent在所有轮次中的状态、耗时和Token用量，状态取最后一次出现的轮次
+func summarizeRun(rounds []RoundResult) map[string]*runSummary {
+	summaries := make(map[string]*runSummary)
+	for _, round := range rounds {
+		for name, result := range round {
+			summary, ok := summaries[name]
+			if !ok {
+				summary = &runSummary{}
+				summaries[name] = summary
+			}
+			summary.status = result.Status
+			summary.runs += result.Iterations
+			summary.duration += result.Duration
+			summary.tokens += result.Tokens
+		}
+	}
+	return summaries
+}
+
+// annotate 为节点添加执行结果标注
+func (n *diagramNode) annotate(summary *runSummary) {
+	if summary == nil {
+		return
+	}
+	n.status = summary.status
+	n.lines = append(n.lines, fmt.Sprintf("%s · %d次 · %s · %d tokens",
+		summary.status, summary.runs, summary.duration.Round(time.Millisecond), summary.tokens))
+}
+
+// ToMermaid 将依赖图导出为 Mermaid 流程图，rounds 不为空时在节点上标注执行结果
+func (d *DependencyGraph) ToMermaid(rounds []RoundResult) string {
+	nodes, edges := d.diagram(rounds)
+	return renderMermaid("TD", nodes, edges)
+}
+
+// ToDOT 将依赖图导出为 Graphviz DOT，rounds 不为空时在节点上标注执行结果
+func (d *DependencyGraph) ToDOT(rounds []RoundResult) string {
+	nodes, edges := d.diagram(rounds)
+	return renderDOT("DependencyGraph", "TB", nodes, edges)
+}
+
+// diagram 构建依赖图的节点和边
+func (d *DependencyGraph) diagram(rounds []RoundResult) ([]*diagramNode, []*diagramEdge) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	names := make([]string, 0, len(d.nodes))
+	for name := range d.nodes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	ids := make(map[string]string, len(names))
+	for i, name := range names {
+		ids[name] = fmt.Sprintf("n%d", i)
+	}
+
+	summaries := summarizeRun(rounds)
+	var nodes []*diagramNode
+	var edges []*diagramEdge
+	for _, name := range names {
+		node := d.nodes[name]
+		dn := &diagramNode{
+			id: ids[name],
+			lines: []string{
+				name,
+				strings.Join(node.agent.GetCapabilities(), ", "),
+				fmt.Sprintf("能力 %.2f", node.capability),
+			},
+		}
+		dn.annotate(summaries[name])
+		nodes = append(nodes, dn)
+
+		for _, edge := range node.dependencies {
+			var labels []string
+			if edge.from.router != nil {
+				labels = append(labels, "路由")
+			}
+			if edge.condition != nil {
+				label := edge.label
+				if label == "" {
+					label = "条件"
+				}
+				labels = append(labels, label)
+			}
+			edges = append(edges, &diagramEdge{
+				from:   ids[edge.from.agent.Name()],
+				to:     ids[name],
+				label:  strings.Join(labels, " / "),
+				dashed: len(labels) > 0,
+			})
+		}
+	}
+
+	for _, loop := range d.loops {
+		label := fmt.Sprintf("循环 ≤%d次", loop.maxIterations)
+		if loop.label != "" {
+			label = loop.label + " " + label
+		}
+		edges = append(edges, &diagramEdge{
+			from:   ids[loop.from.agent.Name()],
+			to:     ids[loop.to.agent.Name()],
+			label:  label,
+			dashed: true,
+		})
+	}
+	return nodes, edges
+}
+
+// ToMermaid 将组配置导出为 Mermaid 流程图，rounds 不为空时在节点上标注执行结果
+func (g *Group) ToMermaid(rounds []RoundResult) string {
+	nodes, edges := g.diagram(rounds)
+	return renderMermaid("LR", nodes, edges)
+}
+
+// ToDOT 将组配置导出为 Graphviz DOT，rounds 不为空时在节点上标注执行结果
+func (g *Group) ToDOT(rounds []RoundResult) string {
+	nodes, edges := g.diagram(rounds)
+	return renderDOT("Group", "LR", nodes, edges)
+}
+
+// diagram 构建组的节点和边：输入 -> 组 -> 各Agent
+func (g *Group) diagram(rounds []RoundResult) ([]*diagramNode, []*diagramEdge) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	mode := "串行执行"
+	if g.parallel {
+		mode = "并行执行"
+	}
+	groupLines := []string{"Group", fmt.Sprintf("%s · %d轮", mode, g.maxRounds)}
+	edgeLabel := ""
+	if g.selector != nil {
+		groupLines = append(groupLines, "选择器挑选Agent")
+		edgeLabel = "选择"
+	}
+
+	nodes := []*diagramNode{
+		{id: "input", lines: []string{"输入"}},
+		{id: "group", lines: groupLines},
+	}
+	edges := []*diagramEdge{{from: "input", to: "group"}}
+
+	summaries := summarizeRun(rounds)
+	for i, a := range g.agents {
+		expertise, _ := agentProfile(a)
+		lines := []string{a.Name(), expertise}
+		switch a := a.(type) {
+		case *ExpertAgent:
+			if a.Model != "" {
+				lines = append(lines, "模型 "+a.Model)
+			}
+		case *HumanAgent:
+			lines = append(lines, "人类参与者")
+		}
+		dn := &diagramNode{id: fmt.Sprintf("n%d", i), lines: lines}
+		dn.annotate(summaries[a.Name()])
+		nodes = append(nodes, dn)
+		edges = append(edges, &diagramEdge{
+			from:   "group",
+			to:     dn.id,
+			label:  edgeLabel,
+			dashed: g.selector != nil,
+		})
+	}
+	return nodes, edges
+}
+
+// statusColors 执行状态对应的填充色和边框色
+var statusColors = map[ResultStatus][2]string{
+	StatusSuccess: {"#d4edda", "#28a745"},
+	StatusSkipped: {"#e2e3e5", "#6c757d"},
+	StatusFailed:  {"#f8d7da", "#dc3545"},
+}
+
+// renderMermaid 渲染 Mermaid 流程图
+func renderMermaid(direction string, nodes []*diagramNode, edges []*diagramEdge) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("flowchart %s\n", direction))
+
+	for _, node := range nodes {
+		lines := make([]string, len(node.lines))
+		for i, line := range node.lines {
+			lines[i] = strings.ReplaceAll(line, `"`, "#quot;")
+		}
+		sb.WriteString(fmt.Sprintf("    %s[\"%s\"]\n", node.id, strings.Join(lines, "<br/>")))
+	}
+
+	for _, edge := range edges {
+		arrow := "-->"
+		if edge.dashed {
+			arrow = "-.->"
+		}
+		if edge.label != "" {
+			label := strings.ReplaceAll(edge.label, `"`, "#quot;")
+			sb.WriteString(fmt.Sprintf("    %s %s|\"%s\"| %s\n", edge.from, arrow, label, edge.to))
+		} else {
+			sb.WriteString(fmt.Sprintf("    %s %s %s\n", edge.from, arrow, edge.to))
+		}
+	}
+
+	// 按执行状态着色
+	used := make(map[ResultStatus][]string)
+	for _, node := range nodes {
+		if node.status != "" {
+			used[node.status] = append(used[node.status], node.id)
+		}
+	}
+	for _, status := range []ResultStatus{StatusSuccess, StatusSkipped, StatusFailed} {
+		ids, ok := used[status]
+		if !ok {
+			continue
+		}
+		colors := statusColors[status]
+		sb.WriteString(fmt.Sprintf("    classDef %s fill:%s,stroke:%s\n", status, colors[0], colors[1]))
+		sb.WriteString(fmt.Sprintf("    class %s %s\n", strings.Join(ids, ","), status))
+	}
+	return sb.String()
+}
+
+// renderDOT 渲染 Graphviz DOT
+func renderDOT(name, rankdir string, nodes []*diagramNode, edges []*diagramEdge) string {
+	escape := func(s string) string {
+		s = strings.ReplaceAll(s, `\`, `\\`)
+		return strings.ReplaceAll(s, `"`, `\"`)
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("digraph %s {\n", name))
+	sb.WriteString(fmt.Sprintf("    rankdir=%s;\n", rankdir))
+	sb.WriteString("    node [shape=box, style=rounded];\n")
+
+	for _, node := range nodes {
+		lines := make([]string, len(node.lines))
+		for i, line := range node.lines {
+			lines[i] = escape(line)
+		}
+		attrs := fmt.Sprintf(`label="%s"`, strings.Join(lines, `\n`))
+		if colors, ok := statusColors[node.status]; ok {
+			attrs += fmt.Sprintf(`, style="rounded,filled", fillcolor="%s", color="%s"`, colors[0], colors[1])
+		}
+		sb.WriteString(fmt.Sprintf("    %s [%s];\n", node.id, attrs))
+	}
+
+	for _, edge := range edges {
+		var attrs []string
+		if edge.label != "" {
+			attrs = append(attrs, fmt.Sprintf(`label="%s"`, escape(edge.label)))
+		}
+		if edge.dashed {
+			attrs = append(attrs, "style=dashed")
+		}
+		if len(attrs) > 0 {
+			sb.WriteString(fmt.Sprintf("    %s -> %s [%s];\n", edge.from, edge.to, strings.Join(attrs, ", ")))
+		} else {
+			sb.WriteString(fmt.Sprintf("    %s -> %s;\n", edge.from, edge.to))
+		}
+	}
+	sb.WriteString("}\n")
+	return sb.String()
+}
@@ -0,0 +1,68 @@
+package agent
+
+import (
+	"context"
+	"time"
+)
+
+// FailurePolicy Agent执行失败时的处理策略
+type FailurePolicy int
+
+const (
+	FailFast          FailurePolicy = iota // 任一Agent失败立即终止整个运行（默认）
+	ContinueOnError                        // 记录失败并继续执行其他Agent
+	RetryThenContinue                      // 重试指定次数，仍失败则记录并继续
+)
+
+// retryInterval 重试前的等待时间
+const retryInterval = time.Second
+
+// executeWithPolicy 按失败策略执行Agent。
+// FailFast 策略下返回错误；其他策略下失败会以 StatusFailed 结果返回，仅在上下文取消时返回错误。
+func executeWithPolicy(ctx context.Context, a Agent, input string, policy FailurePolicy, maxRetries int) (*AgentResult, error) {
+	attempts := 1
+	if policy == RetryThenContinue && maxRetries > 0 {
+		attempts += maxRetries
+	}
+
+	var err error
+	start := time.Now()
+	tokens := 0
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(retryInterval):
+			}
+		}
+
+		var result string
+		var used int
+		result, used, err = executeCounted(ctx, a, input)
+		tokens += used
+		if err == nil {
+			return &AgentResult{
+				Content:    result,
+				Status:     StatusSuccess,
+				Iterations: 1,
+				Duration:   time.Since(start),
+				Tokens:     tokens,
+			}, nil
+		}
+		if ctx.Err() != nil {
+			return nil, err
+		}
+	}
+
+	if policy == FailFast {
+		return nil, err
+	}
+	return &AgentResult{
+		Status:   StatusFailed,
+		Duration: time.Since(start),
+		Tokens:   tokens,
+		Error:    err.Error(),
+		Err:      err,
+	}, nil
+}
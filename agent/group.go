@@ -9,17 +9,38 @@ import (
 	"time"
 )
 
+// DefaultGroupConcurrency 并行执行时同时执行的Agent数量上限
+const DefaultGroupConcurrency = 4
+
 // Group 支持并行执行的Agent组
 type Group struct {
-	agents       []*ExpertAgent
+	agents      []Agent
+	maxRounds   int
+	callback    OutputCallback
+	selector    AgentSelector // 添加选择器
+	parallel    bool          // 是否并发执行
+	concurrency int           // 并行执行时同时执行的Agent数量上限
+	policy      FailurePolicy // 失败处理策略
+	maxRetries  int           // RetryThenContinue 策略下的重试次数
+	mu          sync.RWMutex  // 只保护配置，执行期间不持有
+	// 检查点
+	store CheckpointStore
+	runID string // 最近一次执行的运行ID
+}
+
+// groupRun Group的一次执行，持有执行开始时的配置快照和本次运行的状态，
+// 同一个Group可以同时进行多次执行
+type groupRun struct {
+	agents       []Agent
 	maxRounds    int
-	roundResults []map[string]string
+	roundResults []RoundResult
 	callback     OutputCallback
-	selector     AgentSelector // 添加选择器
-	parallel     bool          // 是否并发执行
-	mu           sync.RWMutex
-	// 添加memory统一管理
-	memory *MemoryManager
+	selector     AgentSelector
+	parallel     bool
+	concurrency  int
+	policy       FailurePolicy
+	maxRetries   int
+	progress     *checkpointer
 }
 
 // NewGroup 创建新的Agent组
@@ -28,74 +49,214 @@ func NewGroup(maxRounds int, parallel bool, callback OutputCallback) *Group {
 		maxRounds = 1
 	}
 	return &Group{
-		agents:       make([]*ExpertAgent, 0),
-		maxRounds:    maxRounds,
-		roundResults: make([]map[string]string, 0, maxRounds),
-		callback:     callback,
-		parallel:     parallel,
-		memory:       globalMemoryManager,
+		agents:      make([]Agent, 0),
+		maxRounds:   maxRounds,
+		callback:    callback,
+		parallel:    parallel,
+		concurrency: DefaultGroupConcurrency,
 	}
 }
 
-// AddAgent 添加Agent到组
-func (g *Group) AddAgent(agent *ExpertAgent) {
+// AddAgent 添加Agent到组，选择型的 ExpertAgent 会同时设置组的选择器
+func (g *Group) AddAgent(agent Agent) {
 	g.mu.Lock()
 	defer g.mu.Unlock()
 
 	// 设置回调
-	agent.SetCallback(g.callback)
+	setAgentCallback(agent, g.callback)
 	g.agents = append(g.agents, agent)
-	g.selector = agent.selector
+	if expert, ok := agent.(*ExpertAgent); ok {
+		g.selector = expert.selector
+	}
 }
 
-// Execute 执行组内所有Agent
+// Execute 执行组内所有Agent，返回每轮成功执行的Agent输出
 func (g *Group) Execute(ctx context.Context, input string) ([]map[string]string, error) {
+	rounds, err := g.ExecuteDetailed(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	return roundContents(rounds), nil
+}
+
+// ExecuteDetailed 执行组内所有Agent，返回每轮各Agent的执行状态、输出和错误。
+// ctx中没有会话时使用新的会话，多次执行之间不共享记忆
+func (g *Group) ExecuteDetailed(ctx context.Context, input string) ([]RoundResult, error) {
+	run, store := g.newRun()
+	runID := newRunID()
+	g.setRunID(runID)
+	ctx = sessionContext(ctx)
+	return run.run(ctx, store, &Checkpoint{RunID: runID, Input: input})
+}
+
+// newRun 读取当前配置，创建一次执行
+func (g *Group) newRun() (*groupRun, CheckpointStore) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return &groupRun{
+		agents:      g.agentList(),
+		maxRounds:   g.maxRounds,
+		callback:    g.callback,
+		selector:    g.selector,
+		parallel:    g.parallel,
+		concurrency: g.concurrency,
+		policy:      g.policy,
+		maxRetries:  g.maxRetries,
+	}, g.store
+}
+
+func (g *Group) setRunID(runID string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.runID = runID
+}
+
+// SetCallback 设置输出回调，同时更新组内所有Agent的回调
+func (g *Group) SetCallback(callback OutputCallback) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.callback = callback
+	for _, a := range g.agents {
+		setAgentCallback(a, callback)
+	}
+}
+
+// Agents 返回组内的Agent
+func (g *Group) Agents() []Agent {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.agentList()
+}
+
+// SetMaxRounds 设置最大轮数
+func (g *Group) SetMaxRounds(maxRounds int) {
 	g.mu.Lock()
 	defer g.mu.Unlock()
+	if maxRounds < 1 {
+		maxRounds = 1
+	}
+	g.maxRounds = maxRounds
+}
 
-	// 初始化结果存储
-	g.roundResults = make([]map[string]string, 0, g.maxRounds)
+// SetConcurrency 设置并行执行时同时执行的Agent数量上限，小于1时使用 DefaultGroupConcurrency
+func (g *Group) SetConcurrency(n int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if n < 1 {
+		n = DefaultGroupConcurrency
+	}
+	g.concurrency = n
+}
 
-	// 第一轮：所有Agent都参与
-	firstRoundResults, err := g.executeFirstRound(ctx, input)
+// SetCheckpointStore 设置检查点存储，每个Agent完成后都会保存进度
+func (g *Group) SetCheckpointStore(store CheckpointStore) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.store = store
+}
+
+// RunID 返回最近一次执行的运行ID，可用于 Resume
+func (g *Group) RunID() string {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.runID
+}
+
+// Resume 从检查点继续执行指定的运行，已完成的Agent不会重复执行
+func (g *Group) Resume(ctx context.Context, runID string) ([]RoundResult, error) {
+	run, store := g.newRun()
+	cp, err := loadCheckpoint(ctx, store, runID)
 	if err != nil {
 		return nil, err
 	}
-	g.roundResults = append(g.roundResults, firstRoundResults)
+	g.setRunID(runID)
 
-	// 每轮结束时通知回调
-	if g.callback != nil && g.selector == nil && g.maxRounds > 1 {
-		g.callback.OnRoundComplete(0, firstRoundResults)
+	if cp.Done {
+		return cp.Rounds, nil
 	}
 
-	// 后续轮次：选择最合适的Agent
-	currentInput := input + "\n\n" + g.combineRoundResults(0, firstRoundResults)
+	ctx = sessionContext(ctx)
+	SessionFromContext(ctx).Restore(run.agents, cp.Memories)
+	SessionFromContext(ctx).Blackboard().Restore(cp.Board)
+	return run.run(ctx, store, cp)
+}
+
+// run 从检查点记录的位置开始执行
+func (g *groupRun) run(ctx context.Context, store CheckpointStore, cp *Checkpoint) ([]RoundResult, error) {
+	g.progress = newCheckpointer(store, cp, SessionFromContext(ctx), func() []Agent { return g.agents })
+	g.callback = callbackFor(ctx, g.callback)
+	input := cp.Input
+
+	// 初始化结果存储，恢复已完成的轮次
+	g.roundResults = make([]RoundResult, 0, g.maxRounds)
+	g.roundResults = append(g.roundResults, cp.Rounds...)
+
+	// 第一轮：所有Agent都参与
+	if len(g.roundResults) == 0 {
+		firstRoundResults, err := g.executeFirstRound(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		g.roundResults = append(g.roundResults, firstRoundResults)
+		g.progress.completeRound(firstRoundResults)
 
-	for round := 1; round < g.maxRounds; round++ {
+		// 每轮结束时通知回调
+		if g.callback != nil && g.selector == nil && g.maxRounds > 1 {
+			g.callback.OnRoundComplete(0, firstRoundResults.Contents())
+		}
+	}
+
+	// 后续轮次：选择最合适的Agent
+	for round := len(g.roundResults); round < g.maxRounds; round++ {
+		currentInput := input + "\n\n" + g.combineRoundResults(round-1, g.roundResults[round-1])
 		roundResults, err := g.executeSubsequentRound(ctx, currentInput, round)
 		if err != nil {
 			return nil, err
 		}
 
 		g.roundResults = append(g.roundResults, roundResults)
+		g.progress.completeRound(roundResults)
 		// 每轮结束时通知回调
 		if g.callback != nil {
-			g.callback.OnRoundComplete(round, roundResults)
+			g.callback.OnRoundComplete(round, roundResults.Contents())
 		}
-		currentInput = input + "\n\n" + g.combineRoundResults(round, roundResults)
 	}
+	g.progress.finish()
 
 	// 通知所有执行完成
 	if g.callback != nil {
-		g.callback.OnAllComplete(g.roundResults)
+		g.callback.OnAllComplete(roundContents(g.roundResults))
 	}
 
-	// 执行完成后清理Memory
-	g.memory.ClearTaskMemory(getOrCreateTaskID())
-
 	return g.roundResults, nil
 }
 
+// SetFailurePolicy 设置Agent执行失败时的处理策略，maxRetries 仅对 RetryThenContinue 生效
+func (g *Group) SetFailurePolicy(policy FailurePolicy, maxRetries int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.policy = policy
+	g.maxRetries = maxRetries
+}
+
+// runAgent 按失败策略执行单个Agent并记录检查点，断点恢复时直接返回已完成的结果
+func (g *groupRun) runAgent(ctx context.Context, a Agent, input string) (*AgentResult, error) {
+	if result, ok := g.progress.completed(a.Name()); ok && result.Status != StatusFailed {
+		return result, nil
+	}
+	result, err := executeWithPolicy(ctx, a, input, g.policy, g.maxRetries)
+	if err != nil {
+		return nil, fmt.Errorf("agent %s execution failed: %w", a.Name(), err)
+	}
+	g.progress.record(a.Name(), result)
+	return result, nil
+}
+
+// agentList 返回组内Agent列表
+func (g *Group) agentList() []Agent {
+	return append([]Agent(nil), g.agents...)
+}
+
 // 添加设置选择器的方法
 func (g *Group) AddSelector(selector AgentSelector) {
 	g.mu.Lock()
@@ -104,18 +265,18 @@ func (g *Group) AddSelector(selector AgentSelector) {
 }
 
 // executeFirstRound 执行第一轮讨论
-func (g *Group) executeFirstRound(ctx context.Context, input string) (map[string]string, error) {
-	var selectedAgents []*ExpertAgent
+func (g *groupRun) executeFirstRound(ctx context.Context, input string) (RoundResult, error) {
+	var selectedAgents []Agent
 	// 如果设置了选择器且不是并行执行，使用选择器选择Agent
 	if g.selector != nil {
 		// 选择最合适的Agent
 		selectedAgents = g.selector.SelectAgents(input, g.agents, 1) // 限制为1个Agent
 		if len(selectedAgents) > 0 {
 			// 执行选中的Agent
-			results := make(map[string]string)
-			result, err := selectedAgents[0].Execute(ctx, input)
+			results := make(RoundResult)
+			result, err := g.runAgent(ctx, selectedAgents[0], input)
 			if err != nil {
-				return nil, fmt.Errorf("agent %s execution failed: %w", selectedAgents[0].Name(), err)
+				return nil, err
 			}
 			results[selectedAgents[0].Name()] = result
 			return results, nil
@@ -138,9 +299,9 @@ func (g *Group) executeFirstRound(ctx context.Context, input string) (map[string
 }
 
 // sortAgentsByCapability 按能力值排序Agent
-func (g *Group) sortAgentsByCapability(agents []*ExpertAgent) []*ExpertAgent {
+func (g *groupRun) sortAgentsByCapability(agents []Agent) []Agent {
 	type agentWithScore struct {
-		agent *ExpertAgent
+		agent Agent
 		score float64
 	}
 
@@ -159,7 +320,7 @@ func (g *Group) sortAgentsByCapability(agents []*ExpertAgent) []*ExpertAgent {
 	})
 
 	// 转换回Agent切片
-	result := make([]*ExpertAgent, len(agents))
+	result := make([]Agent, len(agents))
 	for i, score := range scores {
 		result[i] = score.agent
 	}
@@ -168,16 +329,16 @@ func (g *Group) sortAgentsByCapability(agents []*ExpertAgent) []*ExpertAgent {
 }
 
 // executeSubsequentRound 执行后续轮次
-func (g *Group) executeSubsequentRound(ctx context.Context, input string, round int) (map[string]string, error) {
+func (g *groupRun) executeSubsequentRound(ctx context.Context, input string, round int) (RoundResult, error) {
 	// 如果设置了选择器且不是并行执行，使用选择器选择Agent
 	if g.selector != nil {
 		selectedAgents := g.selector.SelectAgents(input, g.agents, 1) // 限制为1个Agent
 		if len(selectedAgents) > 0 {
 			// 执行选中的Agent
-			results := make(map[string]string)
-			result, err := selectedAgents[0].Execute(ctx, input)
+			results := make(RoundResult)
+			result, err := g.runAgent(ctx, selectedAgents[0], input)
 			if err != nil {
-				return nil, fmt.Errorf("agent %s execution failed: %w", selectedAgents[0].Name(), err)
+				return nil, err
 			}
 			results[selectedAgents[0].Name()] = result
 			return results, nil
@@ -202,49 +363,40 @@ func (g *Group) executeSubsequentRound(ctx context.Context, input string, round
 	return g.executeSerial(ctx, selectedAgents, prompt)
 }
 
-// executeParallel 并行执行Agents
-func (g *Group) executeParallel(ctx context.Context, agents []*ExpertAgent, input string) (map[string]string, error) {
-	results := make(map[string]string)
+// executeParallel 并行执行Agents，最多同时执行 concurrency 个
+func (g *groupRun) executeParallel(ctx context.Context, agents []Agent, input string) (RoundResult, error) {
+	results := make(RoundResult)
 	var mu sync.Mutex
 	errors := make(chan error, len(agents))
 
-	// 创建一个通道来控制执行顺序
-	execChan := make(chan struct{}, 1)
-	execChan <- struct{}{} // 初始令牌
+	limit := g.concurrency
+	if limit < 1 {
+		limit = DefaultGroupConcurrency
+	}
+	sem := make(chan struct{}, limit)
 
 	var wg sync.WaitGroup
 	for _, agent := range agents {
 		wg.Add(1)
-		go func(a *ExpertAgent) {
+		go func(a Agent) {
 			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
 
-			// 获取执行令牌
-			<-execChan
-
-			result, err := a.Execute(ctx, input)
-
-			// 处理结果和错误
+			result, err := g.runAgent(ctx, a, input)
 			if err != nil {
-				errors <- fmt.Errorf("agent %s execution failed: %w", a.Name(), err)
-				execChan <- struct{}{} // 释放令牌给下一个
+				errors <- err
 				return
 			}
 
 			mu.Lock()
 			results[a.Name()] = result
 			mu.Unlock()
-
-			// 等待一小段时间，让输出更自然
-			time.Sleep(500 * time.Millisecond)
-
-			// 释放令牌给下一个Agent
-			execChan <- struct{}{}
 		}(agent)
 	}
 
 	// 等待所有goroutine完成
 	wg.Wait()
-	close(execChan)
 	close(errors)
 
 	// 检查是否有错误
@@ -258,13 +410,13 @@ func (g *Group) executeParallel(ctx context.Context, agents []*ExpertAgent, inpu
 }
 
 // executeSerial 串行执行Agents
-func (g *Group) executeSerial(ctx context.Context, agents []*ExpertAgent, input string) (map[string]string, error) {
-	results := make(map[string]string)
+func (g *groupRun) executeSerial(ctx context.Context, agents []Agent, input string) (RoundResult, error) {
+	results := make(RoundResult)
 
 	for _, agent := range agents {
-		result, err := agent.Execute(ctx, input)
+		result, err := g.runAgent(ctx, agent, input)
 		if err != nil {
-			return nil, fmt.Errorf("agent %s execution failed: %w", agent.Name(), err)
+			return nil, err
 		}
 
 		results[agent.Name()] = result
@@ -276,14 +428,14 @@ func (g *Group) executeSerial(ctx context.Context, agents []*ExpertAgent, input
 }
 
 // selectTopAgents 选择能力值最高的Agent
-func (g *Group) selectTopAgents(count int) []*ExpertAgent {
+func (g *groupRun) selectTopAgents(count int) []Agent {
 	if count > len(g.agents) {
 		count = len(g.agents)
 	}
 
 	// 创建带有能力值的Agent切片
 	type agentWithScore struct {
-		agent *ExpertAgent
+		agent Agent
 		score float64
 	}
 
@@ -301,7 +453,7 @@ func (g *Group) selectTopAgents(count int) []*ExpertAgent {
 	})
 
 	// 选择前count个Agent
-	result := make([]*ExpertAgent, count)
+	result := make([]Agent, count)
 	for i := 0; i < count; i++ {
 		result[i] = scores[i].agent
 	}
@@ -310,7 +462,7 @@ func (g *Group) selectTopAgents(count int) []*ExpertAgent {
 }
 
 // combineRoundResults 组合一轮的结果
-func (g *Group) combineRoundResults(round int, results map[string]string) string {
+func (g *groupRun) combineRoundResults(round int, results RoundResult) string {
 	var sb strings.Builder
 
 	// 添加历史讨论记录
@@ -318,7 +470,7 @@ func (g *Group) combineRoundResults(round int, results map[string]string) string
 		sb.WriteString("前面的讨论总结：\n")
 		for i := 0; i < round; i++ {
 			sb.WriteString(fmt.Sprintf("\n第 %d 轮讨论：\n", i+1))
-			for name, result := range g.roundResults[i] {
+			for name, result := range g.roundResults[i].Contents() {
 				sb.WriteString(fmt.Sprintf("[%s]: %s\n", name, result))
 			}
 		}
@@ -327,7 +479,7 @@ func (g *Group) combineRoundResults(round int, results map[string]string) string
 
 	// 添加本轮讨论结果
 	sb.WriteString("\n本轮讨论内容：\n")
-	for name, result := range results {
+	for name, result := range results.Contents() {
 		sb.WriteString(fmt.Sprintf("[%s]: %s\n", name, result))
 	}
 
@@ -0,0 +1,201 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"multi-agent/config"
+	"multi-agent/oneapi"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeLLM 模拟 OpenAI 兼容接口，回复最后一条用户消息，每次请求用量为 tokensPerCall
+type fakeLLM struct {
+	delay    time.Duration
+	inFlight int32
+	maxSeen  int32
+	failAt   int32 // 第 failAt 次请求返回服务错误，为0时不失败
+	calls    int32
+
+	mu       sync.Mutex
+	answered []string // 成功回复过的请求，系统提示词和最后一条消息
+}
+
+const tokensPerCall = 10
+
+func (f *fakeLLM) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	n := atomic.AddInt32(&f.inFlight, 1)
+	defer atomic.AddInt32(&f.inFlight, -1)
+	for {
+		seen := atomic.LoadInt32(&f.maxSeen)
+		if n <= seen || atomic.CompareAndSwapInt32(&f.maxSeen, seen, n) {
+			break
+		}
+	}
+	time.Sleep(f.delay)
+	if atomic.AddInt32(&f.calls, 1) == f.failAt {
+		http.Error(w, "service unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	var req oneapi.ChatCompletionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	last := req.Messages[len(req.Messages)-1].Content
+	f.mu.Lock()
+	f.answered = append(f.answered, req.Messages[0].Content+"\n"+last)
+	f.mu.Unlock()
+	json.NewEncoder(w).Encode(oneapi.ChatCompletionResponse{
+		ID:      "chatcmpl-test",
+		Object:  "chat.completion",
+		Model:   req.Model,
+		Choices: []oneapi.Choice{{Message: oneapi.ChatMessage{Role: "assistant", Content: "reply: " + last}}},
+		Usage:   oneapi.Usage{TotalTokens: tokensPerCall},
+	})
+}
+
+// useFakeLLM 启动模拟接口并让之后创建的Agent使用它
+func useFakeLLM(t *testing.T, llm http.Handler) {
+	t.Helper()
+	srv := httptest.NewServer(llm)
+	t.Cleanup(srv.Close)
+
+	path := filepath.Join(t.TempDir(), "config.json")
+	data, _ := json.Marshal(config.Config{APIKey: "test", BaseURL: srv.URL, Model: "fake"})
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	old := config.Path
+	config.Path = path
+	t.Cleanup(func() { config.Path = old })
+}
+
+func TestExpertAgentConcurrentSessions(t *testing.T) {
+	useFakeLLM(t, &fakeLLM{delay: 10 * time.Millisecond})
+	a := NewAgent("writer", "写作", "负责撰写")
+
+	sessions := []*Session{NewSession("a"), NewSession("b")}
+	const calls = 5
+	var wg sync.WaitGroup
+	errs := make(chan error, len(sessions)*calls)
+	for _, s := range sessions {
+		wg.Add(1)
+		go func(s *Session) {
+			defer wg.Done()
+			ctx := WithSession(context.Background(), s)
+			for i := 0; i < calls; i++ {
+				input := fmt.Sprintf("%s-%d", s.ID(), i)
+				output, usage, err := a.ExecuteWithUsage(ctx, input)
+				if err != nil {
+					errs <- err
+					return
+				}
+				if !strings.Contains(output, input) {
+					errs <- fmt.Errorf("output %q does not answer %q", output, input)
+				}
+				if usage.TotalTokens != tokensPerCall {
+					errs <- fmt.Errorf("usage = %d, want %d", usage.TotalTokens, tokensPerCall)
+				}
+			}
+		}(s)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	// 每个会话的记忆只包含自己的对话
+	for _, s := range sessions {
+		history := s.Memory(a.Name()).GetHistory()
+		if len(history) != calls*2 {
+			t.Fatalf("session %s has %d messages, want %d", s.ID(), len(history), calls*2)
+		}
+		for _, msg := range history {
+			if !strings.Contains(msg.Content, s.ID()+"-") {
+				t.Errorf("session %s contains foreign message %q", s.ID(), msg.Content)
+			}
+		}
+	}
+}
+
+func TestMemoryGetHistoryReturnsCopy(t *testing.T) {
+	m := NewMemory()
+	m.AddMessage(oneapi.ChatMessage{
+		Role:      "assistant",
+		Content:   "original",
+		ToolCalls: []oneapi.ToolCall{{ID: "call_1"}},
+	})
+
+	history := m.GetHistory()
+	history[0].Content = "changed"
+	history[0].ToolCalls[0].ID = "changed"
+	_ = append(history[:0], oneapi.ChatMessage{Content: "appended"})
+
+	got := m.GetHistory()
+	if len(got) != 1 || got[0].Content != "original" || got[0].ToolCalls[0].ID != "call_1" {
+		t.Fatalf("memory modified through GetHistory: %+v", got)
+	}
+}
+
+func TestGroupParallelConcurrency(t *testing.T) {
+	llm := &fakeLLM{delay: 50 * time.Millisecond}
+	useFakeLLM(t, llm)
+
+	g := NewGroup(1, true, nil)
+	g.SetConcurrency(2)
+	for _, name := range []string{"a", "b", "c", "d"} {
+		g.AddAgent(NewAgent(name, "领域"+name, "专家"+name))
+	}
+
+	rounds, err := g.ExecuteDetailed(context.Background(), "主题")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rounds) != 1 || len(rounds[0]) != 4 {
+		t.Fatalf("got %d rounds with %v", len(rounds), rounds)
+	}
+	for name, result := range rounds[0] {
+		if result.Status != StatusSuccess || result.Tokens != tokensPerCall {
+			t.Errorf("%s: status %s, tokens %d", name, result.Status, result.Tokens)
+		}
+	}
+	if got := atomic.LoadInt32(&llm.maxSeen); got != 2 {
+		t.Errorf("max concurrent requests = %d, want 2", got)
+	}
+}
+
+func TestGroupConcurrentExecute(t *testing.T) {
+	useFakeLLM(t, &fakeLLM{delay: 10 * time.Millisecond})
+
+	g := NewGroup(2, true, nil)
+	g.AddAgent(NewAgent("a", "领域a", "专家a"))
+	g.AddAgent(NewAgent("b", "领域b", "专家b"))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			rounds, err := g.ExecuteDetailed(context.Background(), fmt.Sprintf("主题%d", i))
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if len(rounds) != 2 {
+				t.Errorf("run %d: got %d rounds, want 2", i, len(rounds))
+			}
+		}(i)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,275 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"multi-agent/oneapi"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultHumanTimeout 等待人类参与者回复的默认时间
+const DefaultHumanTimeout = 10 * time.Minute
+
+// HumanPrompt 等待人类参与者回复的请求
+type HumanPrompt struct {
+	ID        string    `json:"id"`
+	Agent     string    `json:"agent"`
+	Input     string    `json:"input"`             // 本次收到的输入，如讨论主题和上一轮的发言
+	Context   []string  `json:"context,omitempty"` // 输入之外其他参与者的新发言，格式为 "[名称]: 内容"
+	SessionID string    `json:"session_id,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// HumanInput 人类参与者的输入来源
+type HumanInput interface {
+	// Read 展示请求并等待回复，ctx 取消或超时时返回错误
+	Read(ctx context.Context, prompt HumanPrompt) (string, error)
+}
+
+var humanSeq uint64
+
+// HumanAgent 由人类发言的Agent
+//
+// 它与其他Agent一样可以加入 Group 或依赖图、被选择器选中，结果和运行记录中也与其他Agent相同；
+// 执行时不调用模型，而是等待 HumanInput 返回人类的回复，超时返回 ErrHumanTimeout。
+type HumanAgent struct {
+	*BaseAgent
+	expertise   string         // 专业领域
+	description string         // 参与者描述
+	input       HumanInput     // 输入来源
+	timeout     time.Duration  // 等待回复的时间，0表示一直等待
+	callback    OutputCallback // 回调函数
+	mu          sync.Mutex
+}
+
+// NewHumanAgent 创建由人类发言的Agent，等待回复的时间默认为 DefaultHumanTimeout
+func NewHumanAgent(name string, expertise string, description string, input HumanInput) *HumanAgent {
+	return &HumanAgent{
+		BaseAgent:   NewBaseAgent(name, []string{expertise}, nil, nil, ""),
+		expertise:   expertise,
+		description: description,
+		input:       input,
+		timeout:     DefaultHumanTimeout,
+	}
+}
+
+// Expertise 返回专业领域
+func (h *HumanAgent) Expertise() string {
+	return h.expertise
+}
+
+// Description 返回参与者描述
+func (h *HumanAgent) Description() string {
+	return h.description
+}
+
+// SetHumanTimeout 设置等待人类回复的时间，0 表示一直等待
+func (h *HumanAgent) SetHumanTimeout(timeout time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.timeout = timeout
+}
+
+// SetCallback 设置输出回调
+func (h *HumanAgent) SetCallback(callback OutputCallback) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.callback = callback
+}
+
+// Execute 等待人类回复，并像模型回复一样写入记忆、共享发言记录和运行记录
+func (h *HumanAgent) Execute(ctx context.Context, input string) (string, error) {
+	h.mu.Lock()
+	callback, timeout := h.callback, h.timeout
+	h.mu.Unlock()
+	callback = callbackFor(ctx, callback)
+	if callback != nil {
+		callback.OnStart(h.Name())
+		defer callback.OnComplete(h.Name())
+	}
+
+	memory := h.memoryFor(ctx)
+	recorder := h.recorderFor(ctx)
+	prompt := HumanPrompt{
+		ID:        fmt.Sprintf("input_%d_%d", time.Now().UnixNano(), atomic.AddUint64(&humanSeq, 1)),
+		Agent:     h.Name(),
+		Input:     input,
+		CreatedAt: time.Now(),
+	}
+	for _, msg := range h.syncShared(ctx, memory) {
+		prompt.Context = append(prompt.Context, msg.Content)
+	}
+	if s := SessionFromContext(ctx); s != nil {
+		prompt.SessionID = s.ID()
+	}
+	memory.AddMessage(oneapi.ChatMessage{Role: "user", Content: input})
+	recorder.message(h.Name(), oneapi.ChatMessage{Role: "user", Content: input})
+
+	readCtx := ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		readCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	reply, err := h.input.Read(readCtx, prompt)
+	if err != nil {
+		if ctx.Err() == nil && errors.Is(readCtx.Err(), context.DeadlineExceeded) {
+			err = fmt.Errorf("%w: %s 在 %s 内未回复", ErrHumanTimeout, h.Name(), timeout)
+		} else {
+			err = fmt.Errorf("读取人类输入失败: %w", err)
+		}
+		recorder.fail(h.Name(), err)
+		return "", err
+	}
+
+	reply = strings.TrimSpace(reply)
+	if callback != nil {
+		callback.OnContent(h.Name(), reply)
+	}
+	memory.AddMessage(oneapi.ChatMessage{Role: "assistant", Content: reply})
+	recorder.message(h.Name(), oneapi.ChatMessage{Role: "assistant", Content: reply})
+	h.postShared(ctx, reply)
+	return reply, nil
+}
+
+// recorderFor 返回上下文中会话的运行记录器，没有时返回nil
+func (h *HumanAgent) recorderFor(ctx context.Context) *Recorder {
+	s := SessionFromContext(ctx)
+	if s == nil || s.Recorder() == nil {
+		return nil
+	}
+	s.Recorder().agent(TranscriptAgent{Name: h.Name(), Expertise: h.expertise})
+	return s.Recorder()
+}
+
+// TerminalInput 在终端中读取人类参与者的发言，每次读取一行
+type TerminalInput struct {
+	in  *lineReader
+	out io.Writer
+}
+
+// NewTerminalInput 创建终端输入来源，从in读取回复，提示写入out
+func NewTerminalInput(in io.Reader, out io.Writer) *TerminalInput {
+	return &TerminalInput{in: newLineReader(in), out: out}
+}
+
+func (t *TerminalInput) Read(ctx context.Context, prompt HumanPrompt) (string, error) {
+	t.in.mu.Lock()
+	defer t.in.mu.Unlock()
+	fmt.Fprintf(t.out, "\n[%s] 轮到你发言\n", prompt.Agent)
+	for _, msg := range prompt.Context {
+		fmt.Fprintln(t.out, msg)
+	}
+	fmt.Fprintf(t.out, "%s\n%s> ", prompt.Input, prompt.Agent)
+	line, err := t.in.readLine(ctx)
+	if err != nil && ctx.Err() != nil {
+		fmt.Fprintln(t.out)
+	}
+	return line, err
+}
+
+// PendingInput 等待回复的人类输入请求
+type PendingInput struct {
+	HumanPrompt
+	reply chan string
+	once  sync.Once
+}
+
+// Reply 回复请求，只有第一次回复有效，返回本次回复是否被接受
+func (p *PendingInput) Reply(content string) bool {
+	delivered := false
+	p.once.Do(func() {
+		p.reply <- content
+		delivered = true
+	})
+	return delivered
+}
+
+// ChannelInput 通过通道把人类输入请求交给其他组件（如服务端接口）处理
+type ChannelInput struct {
+	prompts chan *PendingInput
+	pending map[string]*PendingInput
+	notify  func(HumanPrompt)
+	mu      sync.Mutex
+}
+
+// NewChannelInput 创建基于通道的输入来源，buffer 为请求通道的缓冲大小
+func NewChannelInput(buffer int) *ChannelInput {
+	return &ChannelInput{
+		prompts: make(chan *PendingInput, buffer),
+		pending: make(map[string]*PendingInput),
+	}
+}
+
+// Prompts 返回输入请求通道，处理方对每个请求调用 Reply
+func (c *ChannelInput) Prompts() <-chan *PendingInput {
+	return c.prompts
+}
+
+// SetNotify 设置新请求的通知函数，调用时请求已可以通过 Reply 按ID回复
+func (c *ChannelInput) SetNotify(notify func(HumanPrompt)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.notify = notify
+}
+
+// Pending 返回尚未回复的请求，sessionID 不为空时只返回该会话的请求，按创建时间排序
+func (c *ChannelInput) Pending(sessionID string) []HumanPrompt {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	list := make([]HumanPrompt, 0, len(c.pending))
+	for _, p := range c.pending {
+		if sessionID == "" || p.SessionID == sessionID {
+			list = append(list, p.HumanPrompt)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].CreatedAt.Before(list[j].CreatedAt)
+	})
+	return list
+}
+
+// Reply 按ID回复请求，请求不存在或已回复时返回false
+func (c *ChannelInput) Reply(id, content string) bool {
+	c.mu.Lock()
+	p, ok := c.pending[id]
+	c.mu.Unlock()
+	return ok && p.Reply(content)
+}
+
+func (c *ChannelInput) Read(ctx context.Context, prompt HumanPrompt) (string, error) {
+	p := &PendingInput{HumanPrompt: prompt, reply: make(chan string, 1)}
+	c.mu.Lock()
+	c.pending[prompt.ID] = p
+	notify := c.notify
+	c.mu.Unlock()
+	if notify != nil {
+		notify(prompt)
+	}
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, prompt.ID)
+		c.mu.Unlock()
+	}()
+
+	select {
+	case c.prompts <- p:
+	case content := <-p.reply:
+		// 处理方通过 Reply 按ID直接回复，未从通道取走请求
+		return content, nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+	select {
+	case content := <-p.reply:
+		return content, nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
@@ -0,0 +1,62 @@
+package agent
+
+import (
+	"context"
+	"testing"
+)
+
+func TestChannelInputReplyByID(t *testing.T) {
+	input := NewChannelInput(0)
+	notified := make(chan HumanPrompt, 1)
+	input.SetNotify(func(prompt HumanPrompt) { notified <- prompt })
+
+	result := make(chan string, 1)
+	go func() {
+		content, err := input.Read(context.Background(), HumanPrompt{ID: "input_1", Agent: "alice", SessionID: "s"})
+		if err != nil {
+			t.Error(err)
+		}
+		result <- content
+	}()
+
+	prompt := <-notified
+	if pending := input.Pending("s"); len(pending) != 1 || pending[0].ID != prompt.ID {
+		t.Fatalf("pending = %+v", pending)
+	}
+	if !input.Reply(prompt.ID, "第一次") {
+		t.Fatal("Reply returned false for a pending prompt")
+	}
+	// 读取方取走回复前请求仍在等待列表中，重复回复也不会被接受
+	if input.Reply(prompt.ID, "第二次") {
+		t.Error("Reply returned true for a prompt that was already answered")
+	}
+	if content := <-result; content != "第一次" {
+		t.Errorf("content = %q, want the first reply", content)
+	}
+	if input.Reply(prompt.ID, "第三次") {
+		t.Error("Reply returned true after the prompt was removed")
+	}
+	if input.Reply("missing", "x") {
+		t.Error("Reply returned true for an unknown prompt")
+	}
+}
+
+func TestPendingInputReplyOnce(t *testing.T) {
+	input := NewChannelInput(0)
+	result := make(chan string, 1)
+	go func() {
+		content, _ := input.Read(context.Background(), HumanPrompt{ID: "input_1"})
+		result <- content
+	}()
+
+	p := <-input.Prompts()
+	if !p.Reply("第一次") {
+		t.Fatal("first reply was not accepted")
+	}
+	if p.Reply("第二次") {
+		t.Error("second reply was accepted")
+	}
+	if content := <-result; content != "第一次" {
+		t.Errorf("content = %q, want the first reply", content)
+	}
+}
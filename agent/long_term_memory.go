@@ -0,0 +1,177 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"multi-agent/oneapi"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// FactExtractor 从一次问答中提取值得长期记住的事实
+type FactExtractor interface {
+	Extract(ctx context.Context, input, output string) ([]string, error)
+}
+
+// LongTermMemory 基于向量索引的长期记忆
+//
+// Agent执行前按输入召回相关的记忆加入提示词，执行后将本次问答（或从中提取的事实）写入索引，
+// 因此不必回放完整的历史也能记起之前会话中的内容。
+type LongTermMemory struct {
+	index     *VectorIndex
+	embedder  Embedder
+	extractor FactExtractor
+	topK      int
+	minScore  float64
+	maxChars  int // 写入索引的单条记忆最大字符数
+}
+
+var memoryItemSeq uint64
+
+// NewLongTermMemory 创建长期记忆，默认召回相似度不低于0.2的前5条
+func NewLongTermMemory(index *VectorIndex, embedder Embedder) *LongTermMemory {
+	return &LongTermMemory{
+		index:    index,
+		embedder: embedder,
+		topK:     5,
+		minScore: 0.2,
+		maxChars: 2000,
+	}
+}
+
+// SetTopK 设置每次召回的最大条数
+func (l *LongTermMemory) SetTopK(topK int) {
+	l.topK = topK
+}
+
+// SetMinScore 设置召回的最低相似度
+func (l *LongTermMemory) SetMinScore(score float64) {
+	l.minScore = score
+}
+
+// SetFactExtractor 设置事实提取器，设置后写入提取出的事实而不是完整的问答
+func (l *LongTermMemory) SetFactExtractor(extractor FactExtractor) {
+	l.extractor = extractor
+}
+
+// Index 返回使用的向量索引
+func (l *LongTermMemory) Index() *VectorIndex {
+	return l.index
+}
+
+// Remember 将文本写入长期记忆，agentName和task作为召回时可过滤的元数据
+func (l *LongTermMemory) Remember(ctx context.Context, agentName, task string, texts ...string) error {
+	if len(texts) == 0 {
+		return nil
+	}
+	compressed := make([]string, len(texts))
+	for i, text := range texts {
+		compressed[i] = compressText(text, l.maxChars)
+	}
+	texts = compressed
+	vectors, err := l.embedder.Embed(ctx, texts)
+	if err != nil {
+		return fmt.Errorf("embed memory failed: %w", err)
+	}
+	now := time.Now()
+	items := make([]MemoryItem, len(texts))
+	for i, text := range texts {
+		items[i] = MemoryItem{
+			ID:     fmt.Sprintf("mem_%d_%d", now.UnixNano(), atomic.AddUint64(&memoryItemSeq, 1)),
+			Text:   text,
+			Agent:  agentName,
+			Task:   task,
+			Time:   now,
+			Vector: vectors[i],
+		}
+	}
+	return l.index.Add(items...)
+}
+
+// Recall 召回与query最相关的记忆
+func (l *LongTermMemory) Recall(ctx context.Context, query string, filter RecallFilter) ([]ScoredItem, error) {
+	if l.index.Len() == 0 {
+		return nil, nil
+	}
+	vectors, err := l.embedder.Embed(ctx, []string{query})
+	if err != nil {
+		return nil, fmt.Errorf("embed query failed: %w", err)
+	}
+	return l.index.Search(vectors[0], l.topK, l.minScore, filter), nil
+}
+
+// rememberExchange 写入一次问答，设置了提取器时只写入提取出的事实
+func (l *LongTermMemory) rememberExchange(ctx context.Context, agentName, task, input, output string) error {
+	texts := []string{fmt.Sprintf("问：%s\n答：%s", input, output)}
+	if l.extractor != nil {
+		facts, err := l.extractor.Extract(ctx, input, output)
+		if err != nil {
+			return fmt.Errorf("extract facts failed: %w", err)
+		}
+		texts = facts
+	}
+	return l.Remember(ctx, agentName, task, texts...)
+}
+
+// formatRecalled 将召回的记忆格式化为系统消息内容
+func formatRecalled(items []ScoredItem) string {
+	var b strings.Builder
+	b.WriteString("以下是与当前问题相关的长期记忆，仅在相关时参考：\n")
+	for _, item := range items {
+		fmt.Fprintf(&b, "- [%s", item.Time.Format("2006-01-02 15:04"))
+		if item.Agent != "" {
+			fmt.Fprintf(&b, " %s", item.Agent)
+		}
+		fmt.Fprintf(&b, "] %s\n", item.Text)
+	}
+	return b.String()
+}
+
+// listMarker 匹配行首的列表符号，如 "- "、"1. "、"2、"
+var listMarker = regexp.MustCompile(`^\s*(?:[-*•]\s*|\d+(?:[.)）]\s+|、))`)
+
+// LLMFactExtractor 使用模型从问答中提取事实
+type LLMFactExtractor struct {
+	model  string
+	client *oneapi.Client
+}
+
+// NewLLMFactExtractor 创建事实提取器，model为空时使用配置文件中的默认模型
+func NewLLMFactExtractor(model string) *LLMFactExtractor {
+	return &LLMFactExtractor{model: model, client: oneapi.NewClient()}
+}
+
+// SetClient 设置使用的客户端
+func (x *LLMFactExtractor) SetClient(client *oneapi.Client) {
+	x.client = client
+}
+
+// Extract 实现 FactExtractor，每行一条事实，没有值得记住的内容时返回空
+func (x *LLMFactExtractor) Extract(ctx context.Context, input, output string) ([]string, error) {
+	req := oneapi.ChatCompletionRequest{
+		Model: x.model,
+		Messages: []oneapi.ChatMessage{
+			{Role: "system", Content: "请从以下问答中提取值得长期记住的事实、结论、偏好或决定，" +
+				"每行一条，每条独立完整、不依赖上下文。没有值得记住的内容时只回复“无”。"},
+			{Role: "user", Content: fmt.Sprintf("问：%s\n答：%s", input, output)},
+		},
+		MaxTokens: maxCompletionTokens,
+	}
+	resp, err := x.client.ChatCompletion(ctx, req, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Choices) == 0 {
+		return nil, nil
+	}
+	var facts []string
+	for _, line := range strings.Split(resp.Choices[0].Message.Content, "\n") {
+		line = strings.TrimSpace(listMarker.ReplaceAllString(line, ""))
+		if line != "" && line != "无" {
+			facts = append(facts, line)
+		}
+	}
+	return facts, nil
+}
@@ -0,0 +1,107 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Loop 循环边：from 执行完成后，若终止条件不成立，则从 to 开始重新执行 to..from 之间的节点
+type Loop struct {
+	from          *Node
+	to            *Node
+	label         string        // 循环说明，用于展示
+	maxIterations int           // 最大迭代次数（包含首次执行）
+	until         EdgeCondition // 终止条件，为空时执行到最大迭代次数
+}
+
+// AddLoop 添加循环边，例如 reviewer 未通过时回到 writer 重新执行，最多 maxIterations 次。
+// to 必须是 from 自身或 from 的（间接）依赖，因此需要先添加依赖关系。
+//
+// 循环只在按依赖顺序执行的第一轮中生效，后续轮次按能力分数选择Agent，不再迭代循环；
+// 第一轮中被跳过的节点在后续轮次中保持跳过，不会重新判断条件。
+func (d *DependencyGraph) AddLoop(from, to, label string, maxIterations int, until EdgeCondition) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	fromNode, ok := d.nodes[from]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrAgentNotFound, from)
+	}
+	toNode, ok := d.nodes[to]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrAgentNotFound, to)
+	}
+	if fromNode != toNode && !dependsOn(fromNode, toNode) {
+		return fmt.Errorf("%w: loop target %s is not upstream of %s", ErrInvalidDependency, to, from)
+	}
+	if maxIterations < 1 {
+		maxIterations = 1
+	}
+
+	d.loops = append(d.loops, &Loop{
+		from:          fromNode,
+		to:            toNode,
+		label:         label,
+		maxIterations: maxIterations,
+		until:         until,
+	})
+	return nil
+}
+
+// dependsOn 判断 node 是否（间接）依赖 target
+func dependsOn(node, target *Node) bool {
+	seen := make(map[*Node]bool)
+	var walk func(*Node) bool
+	walk = func(n *Node) bool {
+		for _, edge := range n.dependencies {
+			if edge.from == target {
+				return true
+			}
+			if seen[edge.from] {
+				continue
+			}
+			seen[edge.from] = true
+			if walk(edge.from) {
+				return true
+			}
+		}
+		return false
+	}
+	return walk(node)
+}
+
+// loopBody 返回循环体内的节点（按依赖顺序），即位于 to 与 from 之间路径上的所有节点
+func (d *graphRun) loopBody(loop *Loop) []*Node {
+	inBody := func(n *Node) bool {
+		return (n == loop.to || dependsOn(n, loop.to)) && (n == loop.from || dependsOn(loop.from, n))
+	}
+
+	var body []*Node
+	added := make(map[*Node]bool)
+	var collect func(*Node)
+	collect = func(n *Node) {
+		if added[n] || !inBody(n) {
+			return
+		}
+		added[n] = true
+		for _, edge := range n.dependencies {
+			collect(edge.from)
+		}
+		body = append(body, n)
+	}
+	collect(loop.from)
+	return body
+}
+
+// buildLoopPrompt 构建循环迭代的提示词，附带上一次迭代中循环体的输出
+func (d *graphRun) buildLoopPrompt(topic string, iteration int, body []*Node, outputs map[string]string) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("这是第 %d 次迭代。\n\n讨论主题：\n%s\n\n上一次迭代的输出：\n", iteration, topic))
+	for _, node := range body {
+		if output, ok := outputs[node.agent.Name()]; ok {
+			sb.WriteString(fmt.Sprintf("[%s]: %s\n", node.agent.Name(), output))
+		}
+	}
+	sb.WriteString("\n请根据上述反馈改进你的输出。")
+	return sb.String()
+}
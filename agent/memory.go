@@ -1,14 +1,25 @@
 package agent
 
 import (
+	"context"
+	"fmt"
+	"log"
 	"multi-agent/oneapi"
 	"sync"
 )
 
-// Memory 用于存储Agent的对话历史
+// Memory 用于存储Agent的对话历史，可以被多个Agent并发使用
 type Memory struct {
 	history []oneapi.ChatMessage
 	maxSize int
+	mu      sync.RWMutex
+
+	generation uint64 // 历史被删除或替换时递增，用于判断整理期间历史是否变化
+	compacting bool   // 是否正在整理，同一时间只进行一次整理
+
+	store     MemoryStore // 持久化存储，为nil时只保存在内存中
+	sessionID string
+	name      string
 }
 
 // NewMemory 创建新的记忆存储
@@ -19,57 +30,189 @@ func NewMemory() *Memory {
 	}
 }
 
+// NewPersistentMemory 创建持久化的记忆存储，并从存储中加载最近的消息
+//
+// 之后的修改会同步写入存储，写入失败时记录日志，不影响内存中的记忆。
+func NewPersistentMemory(store MemoryStore, sessionID, name string) (*Memory, error) {
+	history, err := store.Load(sessionID, name)
+	if err != nil {
+		return nil, fmt.Errorf("load memory failed: %w", err)
+	}
+	m := newStoredMemory(store, sessionID, name)
+	for _, msg := range history {
+		m.add(msg)
+	}
+	return m, nil
+}
+
+// newStoredMemory 创建写入store的空记忆，不从存储加载
+func newStoredMemory(store MemoryStore, sessionID, name string) *Memory {
+	m := NewMemory()
+	m.store, m.sessionID, m.name = store, sessionID, name
+	return m
+}
+
 // AddMessage 添加新的对话消息
 func (m *Memory) AddMessage(msg oneapi.ChatMessage) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.add(msg)
+	if m.store != nil {
+		// 超出容量时只在内存中删除，加载时只取最近的消息
+		if err := m.store.Append(m.sessionID, m.name, msg); err != nil {
+			log.Printf("保存记忆失败: %v", err)
+		}
+	}
+}
+
+// persist 将全部历史写入存储，调用方需持有写锁
+func (m *Memory) persist() {
+	if m.store == nil {
+		return
+	}
+	if err := m.store.Replace(m.sessionID, m.name, m.history); err != nil {
+		log.Printf("保存记忆失败: %v", err)
+	}
+}
+
+// add 添加消息，调用方需持有写锁
+func (m *Memory) add(msg oneapi.ChatMessage) {
 	if len(m.history) >= m.maxSize {
 		// 如果超出最大容量，删除最早的消息
 		m.history = m.history[1:]
+		m.generation++
 	}
-	m.history = append(m.history, msg)
+	m.history = append(m.history, copyMessage(msg))
 }
 
-// GetHistory 获取所有历史记录
+// GetHistory 获取所有历史记录的副本，修改返回值不会影响记忆
 func (m *Memory) GetHistory() []oneapi.ChatMessage {
-	return m.history
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	history := make([]oneapi.ChatMessage, len(m.history))
+	for i, msg := range m.history {
+		history[i] = copyMessage(msg)
+	}
+	return history
+}
+
+// Len 返回历史记录条数
+func (m *Memory) Len() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.history)
+}
+
+// Replace 用给定的消息替换全部历史记录
+func (m *Memory) Replace(history []oneapi.ChatMessage) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.history = make([]oneapi.ChatMessage, 0, len(history))
+	m.generation++
+	for _, msg := range history {
+		m.add(msg)
+	}
+	m.persist()
 }
 
 // Clear 清空历史记录
 func (m *Memory) Clear() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	m.history = make([]oneapi.ChatMessage, 0)
+	m.generation++
+	m.persist()
+}
+
+// Compact 使用策略整理历史记录
+//
+// 整理（如调用模型摘要）期间不持有锁，新增的消息会保留在整理结果之后；
+// 若期间历史被删除或替换，则放弃本次结果。已有整理在进行时直接返回。
+func (m *Memory) Compact(ctx context.Context, strategy MemoryStrategy) error {
+	m.mu.Lock()
+	if m.compacting {
+		m.mu.Unlock()
+		return nil
+	}
+	m.compacting = true
+	generation := m.generation
+	history := make([]oneapi.ChatMessage, len(m.history))
+	for i, msg := range m.history {
+		history[i] = copyMessage(msg)
+	}
+	m.mu.Unlock()
+
+	compacted, err := strategy.Compact(ctx, history)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.compacting = false
+	if compacted == nil || m.generation != generation {
+		return err
+	}
+	newer := m.history[len(history):]
+	m.history = make([]oneapi.ChatMessage, 0, len(compacted)+len(newer))
+	for _, msg := range compacted {
+		m.history = append(m.history, copyMessage(msg))
+	}
+	m.history = append(m.history, newer...)
+	m.generation++
+	m.persist()
+	return err
+}
+
+// copyMessage 复制消息，避免与调用方共享工具调用切片
+func copyMessage(msg oneapi.ChatMessage) oneapi.ChatMessage {
+	if msg.ToolCalls != nil {
+		msg.ToolCalls = append([]oneapi.ToolCall(nil), msg.ToolCalls...)
+	}
+	return msg
 }
 
 // MemoryManager 管理不同任务的Memory实例
+//
+// Deprecated: 记忆已按会话隔离，请为每次执行创建 Session 并通过 WithSession 传入。
+// MemoryManager 为每个任务保留一个会话，GetMemory 返回该会话的共享发言记录。
 type MemoryManager struct {
-	memories map[string]*Memory
+	sessions map[string]*Session
 	mutex    sync.RWMutex
 }
 
 // NewMemoryManager 创建新的MemoryManager
+//
+// Deprecated: 使用 NewSession。
 func NewMemoryManager() *MemoryManager {
 	return &MemoryManager{
-		memories: make(map[string]*Memory),
-		mutex:    sync.RWMutex{},
+		sessions: make(map[string]*Session),
 	}
 }
 
-// GetMemory 获取指定任务的Memory实例，如果不存在则创建新的
-func (mm *MemoryManager) GetMemory(taskID string) *Memory {
+// Session 获取指定任务的会话，如果不存在则创建新的
+func (mm *MemoryManager) Session(taskID string) *Session {
 	mm.mutex.Lock()
 	defer mm.mutex.Unlock()
 
-	if memory, exists := mm.memories[taskID]; exists {
-		return memory
+	if session, exists := mm.sessions[taskID]; exists {
+		return session
 	}
+	session := NewSession(taskID)
+	mm.sessions[taskID] = session
+	return session
+}
 
-	memory := NewMemory()
-	mm.memories[taskID] = memory
-	return memory
+// GetMemory 获取指定任务的Memory实例，如果不存在则创建新的
+func (mm *MemoryManager) GetMemory(taskID string) *Memory {
+	return mm.Session(taskID).Shared()
 }
 
 // ClearTaskMemory 清理指定任务的Memory
 func (mm *MemoryManager) ClearTaskMemory(taskID string) {
 	mm.mutex.Lock()
-	defer mm.mutex.Unlock()
+	session, exists := mm.sessions[taskID]
+	delete(mm.sessions, taskID)
+	mm.mutex.Unlock()
 
-	delete(mm.memories, taskID)
+	if exists {
+		session.Reset()
+	}
 }
@@ -0,0 +1,221 @@
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"multi-agent/oneapi"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryStore 记忆的持久化存储，按会话ID和记忆名称保存对话消息
+//
+// 使用固定的会话ID（如用户或助手的标识）可以让长期运行的助手在重启后记住之前的对话。
+type MemoryStore interface {
+	// Load 读取记忆中的全部消息，没有记录时返回空
+	Load(sessionID, name string) ([]oneapi.ChatMessage, error)
+	// Append 追加一条消息
+	Append(sessionID, name string, msg oneapi.ChatMessage) error
+	// Replace 用给定的消息替换记忆中的全部消息
+	Replace(sessionID, name string, history []oneapi.ChatMessage) error
+	// Memories 返回会话中所有记忆的名称
+	Memories(sessionID string) ([]string, error)
+	// Sessions 返回所有会话ID
+	Sessions() ([]string, error)
+	// Delete 删除会话的全部记忆
+	Delete(sessionID string) error
+}
+
+// memoryRecord JSONL文件中的一行
+type memoryRecord struct {
+	Memory  string             `json:"memory"`
+	Time    time.Time          `json:"time"`
+	Message oneapi.ChatMessage `json:"message"`
+}
+
+// FileMemoryStore 基于JSONL文件的记忆存储，每个会话一个文件，每行一条消息，便于直接查看
+type FileMemoryStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewFileMemoryStore 创建文件记忆存储，目录不存在时自动创建
+func NewFileMemoryStore(dir string) (*FileMemoryStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("create memory dir failed: %w", err)
+	}
+	return &FileMemoryStore{dir: dir}, nil
+}
+
+// path 返回会话文件路径，会话ID经过转义，不会逃出存储目录
+func (s *FileMemoryStore) path(sessionID string) string {
+	return filepath.Join(s.dir, url.PathEscape(sessionID)+".jsonl")
+}
+
+// Load 实现 MemoryStore
+func (s *FileMemoryStore) Load(sessionID, name string) ([]oneapi.ChatMessage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	records, err := s.read(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	var history []oneapi.ChatMessage
+	for _, r := range records {
+		if r.Memory == name {
+			history = append(history, r.Message)
+		}
+	}
+	return history, nil
+}
+
+// Append 实现 MemoryStore
+func (s *FileMemoryStore) Append(sessionID, name string, msg oneapi.ChatMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, err := json.Marshal(memoryRecord{Memory: name, Time: time.Now(), Message: msg})
+	if err != nil {
+		return fmt.Errorf("marshal memory failed: %w", err)
+	}
+	f, err := os.OpenFile(s.path(sessionID), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("open memory file failed: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("write memory failed: %w", err)
+	}
+	return nil
+}
+
+// Replace 实现 MemoryStore，重写会话文件，保留其他记忆的消息
+func (s *FileMemoryStore) Replace(sessionID, name string, history []oneapi.ChatMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	records, err := s.read(sessionID)
+	if err != nil {
+		return err
+	}
+	kept := records[:0]
+	for _, r := range records {
+		if r.Memory != name {
+			kept = append(kept, r)
+		}
+	}
+	now := time.Now()
+	for _, msg := range history {
+		kept = append(kept, memoryRecord{Memory: name, Time: now, Message: msg})
+	}
+	return s.write(sessionID, kept)
+}
+
+// Memories 实现 MemoryStore
+func (s *FileMemoryStore) Memories(sessionID string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	records, err := s.read(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	var names []string
+	for _, r := range records {
+		if !seen[r.Memory] {
+			seen[r.Memory] = true
+			names = append(names, r.Memory)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Sessions 实现 MemoryStore
+func (s *FileMemoryStore) Sessions() ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("read memory dir failed: %w", err)
+	}
+	var ids []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".jsonl") {
+			continue
+		}
+		id, err := url.PathUnescape(strings.TrimSuffix(name, ".jsonl"))
+		if err != nil {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// Delete 实现 MemoryStore
+func (s *FileMemoryStore) Delete(sessionID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := os.Remove(s.path(sessionID)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("delete memory file failed: %w", err)
+	}
+	return nil
+}
+
+// read 读取会话文件的全部记录，调用方需持有锁
+func (s *FileMemoryStore) read(sessionID string) ([]memoryRecord, error) {
+	f, err := os.Open(s.path(sessionID))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("open memory file failed: %w", err)
+	}
+	defer f.Close()
+
+	var records []memoryRecord
+	decoder := json.NewDecoder(f)
+	for {
+		var r memoryRecord
+		if err := decoder.Decode(&r); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("parse memory file %s failed: %w", f.Name(), err)
+		}
+		records = append(records, r)
+	}
+	return records, nil
+}
+
+// write 通过临时文件原子地重写会话文件，调用方需持有锁
+func (s *FileMemoryStore) write(sessionID string, records []memoryRecord) error {
+	tmp, err := os.CreateTemp(s.dir, ".memory-*")
+	if err != nil {
+		return fmt.Errorf("create memory file failed: %w", err)
+	}
+	encoder := json.NewEncoder(tmp)
+	for _, r := range records {
+		if err := encoder.Encode(r); err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+			return fmt.Errorf("write memory failed: %w", err)
+		}
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("write memory failed: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path(sessionID)); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("write memory failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+package agent
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"multi-agent/oneapi"
+	"time"
+
+	_ "modernc.org/sqlite" // 纯Go实现的SQLite驱动，无需CGO
+)
+
+const sqliteMemorySchema = `
+CREATE TABLE IF NOT EXISTS memory_messages (
+	id           INTEGER PRIMARY KEY AUTOINCREMENT,
+	session_id   TEXT NOT NULL,
+	memory       TEXT NOT NULL,
+	role         TEXT NOT NULL,
+	content      TEXT NOT NULL,
+	tool_calls   TEXT,
+	tool_call_id TEXT,
+	created_at   TIMESTAMP NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_memory_messages_session ON memory_messages (session_id, memory, id);
+`
+
+// SQLiteMemoryStore 基于嵌入式SQLite的记忆存储，可以用任意SQLite客户端查询 memory_messages 表
+type SQLiteMemoryStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteMemoryStore 打开（或创建）SQLite数据库文件作为记忆存储
+func NewSQLiteMemoryStore(path string) (*SQLiteMemoryStore, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("open sqlite failed: %w", err)
+	}
+	// SQLite同一时间只允许一个写入者，使用单连接避免 SQLITE_BUSY
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(sqliteMemorySchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("create memory table failed: %w", err)
+	}
+	return &SQLiteMemoryStore{db: db}, nil
+}
+
+// Close 关闭数据库
+func (s *SQLiteMemoryStore) Close() error {
+	return s.db.Close()
+}
+
+// Load 实现 MemoryStore
+func (s *SQLiteMemoryStore) Load(sessionID, name string) ([]oneapi.ChatMessage, error) {
+	rows, err := s.db.Query(`SELECT role, content, tool_calls, tool_call_id FROM memory_messages
+		WHERE session_id = ? AND memory = ? ORDER BY id`, sessionID, name)
+	if err != nil {
+		return nil, fmt.Errorf("query memory failed: %w", err)
+	}
+	defer rows.Close()
+
+	var history []oneapi.ChatMessage
+	for rows.Next() {
+		var msg oneapi.ChatMessage
+		var toolCalls, toolCallID sql.NullString
+		if err := rows.Scan(&msg.Role, &msg.Content, &toolCalls, &toolCallID); err != nil {
+			return nil, fmt.Errorf("scan memory failed: %w", err)
+		}
+		if toolCalls.Valid {
+			if err := json.Unmarshal([]byte(toolCalls.String), &msg.ToolCalls); err != nil {
+				return nil, fmt.Errorf("parse tool calls failed: %w", err)
+			}
+		}
+		msg.ToolCallID = toolCallID.String
+		history = append(history, msg)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("query memory failed: %w", err)
+	}
+	return history, nil
+}
+
+// Append 实现 MemoryStore
+func (s *SQLiteMemoryStore) Append(sessionID, name string, msg oneapi.ChatMessage) error {
+	return s.insert(s.db, sessionID, name, msg, time.Now())
+}
+
+// Replace 实现 MemoryStore
+func (s *SQLiteMemoryStore) Replace(sessionID, name string, history []oneapi.ChatMessage) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin transaction failed: %w", err)
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(`DELETE FROM memory_messages WHERE session_id = ? AND memory = ?`, sessionID, name); err != nil {
+		return fmt.Errorf("delete memory failed: %w", err)
+	}
+	now := time.Now()
+	for _, msg := range history {
+		if err := s.insert(tx, sessionID, name, msg, now); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit memory failed: %w", err)
+	}
+	return nil
+}
+
+// Memories 实现 MemoryStore
+func (s *SQLiteMemoryStore) Memories(sessionID string) ([]string, error) {
+	return s.queryStrings(`SELECT DISTINCT memory FROM memory_messages WHERE session_id = ? ORDER BY memory`, sessionID)
+}
+
+// Sessions 实现 MemoryStore
+func (s *SQLiteMemoryStore) Sessions() ([]string, error) {
+	return s.queryStrings(`SELECT DISTINCT session_id FROM memory_messages ORDER BY session_id`)
+}
+
+// Delete 实现 MemoryStore
+func (s *SQLiteMemoryStore) Delete(sessionID string) error {
+	if _, err := s.db.Exec(`DELETE FROM memory_messages WHERE session_id = ?`, sessionID); err != nil {
+		return fmt.Errorf("delete memory failed: %w", err)
+	}
+	return nil
+}
+
+// execer sql.DB 和 sql.Tx 共有的方法
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+func (s *SQLiteMemoryStore) insert(db execer, sessionID, name string, msg oneapi.ChatMessage, at time.Time) error {
+	var toolCalls, toolCallID sql.NullString
+	if len(msg.ToolCalls) > 0 {
+		data, err := json.Marshal(msg.ToolCalls)
+		if err != nil {
+			return fmt.Errorf("marshal tool calls failed: %w", err)
+		}
+		toolCalls = sql.NullString{String: string(data), Valid: true}
+	}
+	if msg.ToolCallID != "" {
+		toolCallID = sql.NullString{String: msg.ToolCallID, Valid: true}
+	}
+	_, err := db.Exec(`INSERT INTO memory_messages (session_id, memory, role, content, tool_calls, tool_call_id, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`, sessionID, name, msg.Role, msg.Content, toolCalls, toolCallID, at)
+	if err != nil {
+		return fmt.Errorf("insert memory failed: %w", err)
+	}
+	return nil
+}
+
+// queryStrings 执行返回单列文本的查询
+func (s *SQLiteMemoryStore) queryStrings(query string, args ...interface{}) ([]string, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query memory failed: %w", err)
+	}
+	defer rows.Close()
+	var values []string
+	for rows.Next() {
+		var v string
+		if err := rows.Scan(&v); err != nil {
+			return nil, fmt.Errorf("scan memory failed: %w", err)
+		}
+		values = append(values, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("query memory failed: %w", err)
+	}
+	return values, nil
+}
@@ -0,0 +1,166 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"multi-agent/oneapi"
+	"strings"
+)
+
+// MemoryStrategy 记忆整理策略，在每次Agent执行结束后整理对话历史
+//
+// Compact 返回整理后的历史；不需要整理时直接返回原历史。返回错误时，
+// 若同时返回了非nil的历史（例如摘要失败后退化为滑动窗口），该历史仍会被使用。
+type MemoryStrategy interface {
+	Compact(ctx context.Context, history []oneapi.ChatMessage) ([]oneapi.ChatMessage, error)
+}
+
+// summaryPrefix 摘要消息的内容前缀，用于识别已有的摘要
+const summaryPrefix = "以下是之前对话的摘要：\n"
+
+// isSummary 判断消息是否为摘要消息
+func isSummary(msg oneapi.ChatMessage) bool {
+	return msg.Role == "system" && strings.HasPrefix(msg.Content, summaryPrefix)
+}
+
+// SlidingWindowStrategy 滑动窗口策略，只保留最新的若干条消息，已有的摘要消息始终保留
+type SlidingWindowStrategy struct {
+	maxMessages int
+}
+
+// NewSlidingWindowStrategy 创建滑动窗口策略
+func NewSlidingWindowStrategy(maxMessages int) *SlidingWindowStrategy {
+	return &SlidingWindowStrategy{maxMessages: maxMessages}
+}
+
+// Compact 实现 MemoryStrategy
+func (s *SlidingWindowStrategy) Compact(ctx context.Context, history []oneapi.ChatMessage) ([]oneapi.ChatMessage, error) {
+	if s.maxMessages <= 0 || len(history) <= s.maxMessages {
+		return history, nil
+	}
+	var head []oneapi.ChatMessage
+	if isSummary(history[0]) {
+		head = history[:1]
+	}
+	start := skipToolResults(history, len(history)-s.maxMessages+len(head))
+	return append(append([]oneapi.ChatMessage(nil), head...), history[start:]...), nil
+}
+
+// SummaryStrategy 摘要策略，历史超过Token阈值时，将较早的对话交给模型摘要为一条滚动摘要消息
+type SummaryStrategy struct {
+	threshold  int            // 触发摘要的Token数
+	keepLatest int            // 保留原文的最新消息条数
+	model      string         // 摘要使用的模型，可指定更便宜的模型
+	client     *oneapi.Client // 摘要使用的客户端
+}
+
+// NewSummaryStrategy 创建摘要策略，model为空时使用配置文件中的默认模型
+func NewSummaryStrategy(threshold, keepLatest int, model string) *SummaryStrategy {
+	return &SummaryStrategy{
+		threshold:  threshold,
+		keepLatest: keepLatest,
+		model:      model,
+		client:     oneapi.NewClient(),
+	}
+}
+
+// SetClient 设置摘要使用的客户端
+func (s *SummaryStrategy) SetClient(client *oneapi.Client) {
+	s.client = client
+}
+
+// Compact 实现 MemoryStrategy
+func (s *SummaryStrategy) Compact(ctx context.Context, history []oneapi.ChatMessage) ([]oneapi.ChatMessage, error) {
+	if oneapi.CountMessagesTokens(history) <= s.threshold {
+		return history, nil
+	}
+	cut := len(history) - s.keepLatest
+	if cut < 0 {
+		cut = 0
+	}
+	// 工具结果与对应的工具调用一起摘要
+	cut = skipToolResults(history, cut)
+	if cut == 0 || (cut == 1 && isSummary(history[0])) {
+		return history, nil
+	}
+
+	summary, err := s.summarize(ctx, history[:cut])
+	if err != nil {
+		return nil, err
+	}
+	compacted := make([]oneapi.ChatMessage, 0, len(history)-cut+1)
+	compacted = append(compacted, oneapi.ChatMessage{Role: "system", Content: summaryPrefix + summary})
+	return append(compacted, history[cut:]...), nil
+}
+
+// summarize 调用模型将消息摘要，已有的摘要会被合并到新摘要中
+func (s *SummaryStrategy) summarize(ctx context.Context, messages []oneapi.ChatMessage) (string, error) {
+	var transcript strings.Builder
+	for _, msg := range messages {
+		switch {
+		case isSummary(msg):
+			transcript.WriteString("[之前的摘要]\n")
+			transcript.WriteString(strings.TrimPrefix(msg.Content, summaryPrefix))
+		case len(msg.ToolCalls) > 0:
+			for _, call := range msg.ToolCalls {
+				fmt.Fprintf(&transcript, "[assistant 调用工具 %s] %s", call.Function.Name, call.Function.Arguments)
+			}
+		default:
+			fmt.Fprintf(&transcript, "[%s] %s", msg.Role, msg.Content)
+		}
+		transcript.WriteString("\n\n")
+	}
+
+	req := oneapi.ChatCompletionRequest{
+		Model: s.model,
+		Messages: []oneapi.ChatMessage{
+			{Role: "system", Content: "你是对话摘要助手。请将以下多轮讨论压缩为一份简洁的摘要，" +
+				"保留各方的主要观点、已达成的结论、关键数据和尚未解决的问题，不要添加原文没有的内容。" +
+				"如果包含之前的摘要，请将其与新的对话合并为一份完整的摘要。"},
+			{Role: "user", Content: transcript.String()},
+		},
+		MaxTokens: maxCompletionTokens,
+	}
+	resp, err := s.client.ChatCompletion(ctx, req, nil)
+	if err != nil {
+		return "", fmt.Errorf("summarize memory failed: %w", err)
+	}
+	if len(resp.Choices) == 0 || resp.Choices[0].Message.Content == "" {
+		return "", errors.New("summarize memory failed: empty summary")
+	}
+	return resp.Choices[0].Message.Content, nil
+}
+
+// HybridStrategy 混合策略，先尝试摘要，再用滑动窗口限制消息条数；摘要失败时仍按窗口丢弃
+type HybridStrategy struct {
+	summary *SummaryStrategy
+	window  *SlidingWindowStrategy
+}
+
+// NewHybridStrategy 创建混合策略
+func NewHybridStrategy(summary *SummaryStrategy, maxMessages int) *HybridStrategy {
+	return &HybridStrategy{
+		summary: summary,
+		window:  NewSlidingWindowStrategy(maxMessages),
+	}
+}
+
+// Compact 实现 MemoryStrategy
+func (s *HybridStrategy) Compact(ctx context.Context, history []oneapi.ChatMessage) ([]oneapi.ChatMessage, error) {
+	compacted, err := s.summary.Compact(ctx, history)
+	if err != nil {
+		// 摘要失败时退化为滑动窗口，避免记忆无限增长
+		compacted = history
+	}
+	windowed, _ := s.window.Compact(ctx, compacted)
+	return windowed, err
+}
+
+// skipToolResults 从start开始跳过工具结果，避免保留的历史以缺少工具调用的工具结果开头
+func skipToolResults(history []oneapi.ChatMessage, start int) int {
+	for start < len(history) && history[start].Role == "tool" {
+		start++
+	}
+	return start
+}
@@ -1,5 +1,7 @@
 package agent
 
+import "context"
+
 // OutputCallback 定义输出回调接口
 type OutputCallback interface {
 	// OnStart 当Agent开始输出时调用
@@ -17,3 +19,43 @@ type OutputCallback interface {
 	// OnComplete 当整个讨论完成时调用
 	OnAllComplete(allResults []map[string]string)
 }
+
+// callbackKey 上下文中输出回调的键
+type callbackKey struct{}
+
+// callbackValue 包装上下文中的回调，以区分"未设置"和"设置为nil"
+type callbackValue struct {
+	callback OutputCallback
+}
+
+// WithCallback 返回携带输出回调的上下文。上下文中的回调优先于Agent、Group和依赖图自身的回调，
+// 只作用于使用该上下文的这次执行，例如一个HTTP请求；callback为nil表示这次执行不输出
+func WithCallback(ctx context.Context, callback OutputCallback) context.Context {
+	return context.WithValue(ctx, callbackKey{}, callbackValue{callback: callback})
+}
+
+// CallbackFromContext 返回上下文中的输出回调，ok表示上下文是否设置了回调
+func CallbackFromContext(ctx context.Context) (callback OutputCallback, ok bool) {
+	v, ok := ctx.Value(callbackKey{}).(callbackValue)
+	return v.callback, ok
+}
+
+// callbackFor 返回本次执行使用的回调：上下文设置了回调时使用它，否则使用fallback
+func callbackFor(ctx context.Context, fallback OutputCallback) OutputCallback {
+	if callback, ok := CallbackFromContext(ctx); ok {
+		return callback
+	}
+	return fallback
+}
+
+// callbackReceiver 可以设置输出回调的Agent
+type callbackReceiver interface {
+	SetCallback(callback OutputCallback)
+}
+
+// setAgentCallback 为支持输出回调的Agent设置回调
+func setAgentCallback(a Agent, callback OutputCallback) {
+	if r, ok := a.(callbackReceiver); ok {
+		r.SetCallback(callback)
+	}
+}
@@ -0,0 +1,88 @@
+package agent
+
+import (
+	"context"
+	"multi-agent/oneapi"
+	"time"
+)
+
+// ResultStatus Agent在一轮讨论中的执行状态
+type ResultStatus string
+
+const (
+	StatusSuccess ResultStatus = "success" // 正常执行完成
+	StatusSkipped ResultStatus = "skipped" // 条件不满足或未被路由选中而跳过
+	StatusFailed  ResultStatus = "failed"  // 执行失败，错误记录在 Error 中
+)
+
+// AgentResult 单个Agent在一轮讨论中的结果
+type AgentResult struct {
+	Content    string        `json:"content"`              // 输出内容
+	Status     ResultStatus  `json:"status"`               // 执行状态
+	Reason     string        `json:"reason,omitempty"`     // 跳过等状态的原因说明
+	Iterations int           `json:"iterations,omitempty"` // 本轮执行次数，循环体内的节点可能大于1
+	Duration   time.Duration `json:"duration,omitempty"`   // 执行耗时（包含重试）
+	Tokens     int           `json:"tokens,omitempty"`     // Token用量
+	Error      string        `json:"error,omitempty"`      // 失败时的错误信息
+	Err        error         `json:"-"`                    // 失败时的原始错误，不参与序列化
+}
+
+// RoundResult 一轮讨论的结果，按Agent名称索引
+type RoundResult map[string]*AgentResult
+
+// Contents 返回本轮成功执行的Agent输出
+func (r RoundResult) Contents() map[string]string {
+	contents := make(map[string]string, len(r))
+	for name, result := range r {
+		if result.Status == StatusSuccess {
+			contents[name] = result.Content
+		}
+	}
+	return contents
+}
+
+// roundContents 返回每轮成功执行的Agent输出
+func roundContents(rounds []RoundResult) []map[string]string {
+	contents := make([]map[string]string, len(rounds))
+	for i, results := range rounds {
+		contents[i] = results.Contents()
+	}
+	return contents
+}
+
+// Skipped 返回本轮被跳过的Agent名称
+func (r RoundResult) Skipped() []string {
+	var names []string
+	for name, result := range r {
+		if result.Status == StatusSkipped {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// Failed 返回本轮执行失败的Agent及其错误
+func (r RoundResult) Failed() map[string]string {
+	failed := make(map[string]string)
+	for name, result := range r {
+		if result.Status == StatusFailed {
+			failed[name] = result.Error
+		}
+	}
+	return failed
+}
+
+// usageReporter 能够报告每次执行Token用量的Agent
+type usageReporter interface {
+	ExecuteWithUsage(ctx context.Context, input string) (string, oneapi.Usage, error)
+}
+
+// executeCounted 执行Agent并返回本次执行的Token用量，不支持时用量为0
+func executeCounted(ctx context.Context, a Agent, input string) (string, int, error) {
+	if reporter, ok := a.(usageReporter); ok {
+		output, usage, err := reporter.ExecuteWithUsage(ctx, input)
+		return output, usage.TotalTokens, err
+	}
+	output, err := a.Execute(ctx, input)
+	return output, 0, err
+}
@@ -111,9 +111,9 @@ func calculateExperienceScore(agent Agent) float64 {
 
 // calculateExpertiseScore 计算专业度分数
 func calculateExpertiseScore(agent Agent) float64 {
-	if expert, ok := agent.(*ExpertAgent); ok {
+	if expert, ok := agent.(interface{ Description() string }); ok {
 		// 分析描述中的专业术语密度
-		description := expert.description
+		description := expert.Description()
 		terms := extractProfessionalTerms(description)
 		return float64(len(terms)) / float64(len(strings.Fields(description)))
 	}
@@ -4,12 +4,13 @@ import (
 	"context"
 	"fmt"
 	"multi-agent/oneapi"
+	"strings"
 )
 
 // AgentSelector Agent选择器接口
 type AgentSelector interface {
 	// SelectAgents 根据输入选择最合适的Agents
-	SelectAgents(input string, agents []*ExpertAgent, limit int) []*ExpertAgent
+	SelectAgents(input string, agents []Agent, limit int) []Agent
 }
 
 type DefaultSelector struct {
@@ -23,7 +24,7 @@ func NewDefaultSelector() *DefaultSelector {
 }
 
 // SelectAgents 使用LLM进行Agent选择
-func (s *DefaultSelector) SelectAgents(input string, agents []*ExpertAgent, limit int) []*ExpertAgent {
+func (s *DefaultSelector) SelectAgents(input string, agents []Agent, limit int) []Agent {
 	if len(agents) == 0 {
 		return nil
 	}
@@ -38,14 +39,14 @@ func (s *DefaultSelector) SelectAgents(input string, agents []*ExpertAgent, limi
 	}
 
 	if selectedIndex >= 0 && selectedIndex < len(agents) {
-		return []*ExpertAgent{agents[selectedIndex]}
+		return []Agent{agents[selectedIndex]}
 	}
 
 	return nil
 }
 
 // buildHandoffPrompt 构建Handoff提示词
-func (s *DefaultSelector) buildHandoffPrompt(input string, agents []*ExpertAgent) string {
+func (s *DefaultSelector) buildHandoffPrompt(input string, agents []Agent) string {
 	prompt := `As an AI coordinator, analyze the user input and select the most suitable agent based on their expertise and capabilities.
   
   User Input: %s
@@ -64,8 +65,9 @@ func (s *DefaultSelector) buildHandoffPrompt(input string, agents []*ExpertAgent
 	// 构建Agent列表描述
 	var agentDescriptions string
 	for i, agent := range agents {
+		expertise, description := agentProfile(agent)
 		agentDescriptions += fmt.Sprintf("%d. Name: %s\n   Expertise: %s\n   Description: %s\n\n",
-			i, agent.Name(), agent.expertise, agent.description)
+			i, agent.Name(), expertise, description)
 	}
 
 	return fmt.Sprintf(prompt, input, agentDescriptions, len(agents)-1)
@@ -108,3 +110,16 @@ func (s *DefaultSelector) askLLMForSelection(ctx context.Context, prompt string,
 
 	return selectedIndex, nil
 }
+
+// agentProfile 返回Agent的专业领域和描述，Agent未提供时使用能力列表
+func agentProfile(a Agent) (expertise string, description string) {
+	if e, ok := a.(interface{ Expertise() string }); ok {
+		expertise = e.Expertise()
+	} else {
+		expertise = strings.Join(a.GetCapabilities(), ", ")
+	}
+	if d, ok := a.(interface{ Description() string }); ok {
+		description = d.Description()
+	}
+	return expertise, description
+}
@@ -0,0 +1,305 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"multi-agent/oneapi"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// SharedMemoryKey 共享发言记录在 Snapshot 结果和记忆存储中使用的名称
+const SharedMemoryKey = "@shared"
+
+// Session 一次运行的会话，保存该运行中各Agent的对话记忆
+//
+// 每个Agent有自己的私有记忆，只包含它收到的输入和它自己的回复；会话另有一份共享发言记录，
+// 每条消息以 "[名称]: 内容" 注明发言者。Agent执行前会收到其他Agent的新发言，作为注明发言者的
+// 用户消息加入它的私有记忆，因此不会把别人的话当成自己说过的话。
+//
+// 会话通过 context.Context 传递：Agent执行时优先使用上下文中的会话记忆，
+// 不同会话之间互不影响，因此同一进程中可以同时进行多个讨论。
+type Session struct {
+	id       string
+	store    MemoryStore        // 持久化存储，为nil时只保存在内存中
+	memories map[string]*Memory // 各Agent的私有记忆
+	shared   *Memory            // 共享发言记录
+	total    int                // 写入共享发言记录的消息总数，不受容量限制影响
+	cursors  map[string]int     // 各Agent已看到的共享消息数
+	strategy MemoryStrategy     // 会话默认的记忆整理策略
+	board    *Blackboard        // 运行内共享的黑板
+	recorder *Recorder          // 运行记录器，为nil时不记录
+	mu       sync.RWMutex
+}
+
+var sessionSeq uint64
+
+// NewSession 创建会话，id为空时自动生成
+func NewSession(id string) *Session {
+	if id == "" {
+		id = fmt.Sprintf("session_%d_%d", time.Now().UnixNano(), atomic.AddUint64(&sessionSeq, 1))
+	}
+	return &Session{
+		id:       id,
+		memories: make(map[string]*Memory),
+		shared:   NewMemory(),
+		cursors:  make(map[string]int),
+		board:    NewBlackboard(),
+	}
+}
+
+// NewPersistentSession 创建记忆保存在store中的会话，并加载该会话之前的记忆
+//
+// 使用固定的id（如用户标识）可以让长期运行的助手在重启后继续之前的对话。
+func NewPersistentSession(id string, store MemoryStore) (*Session, error) {
+	s := NewSession(id)
+	s.store = store
+	names, err := store.Memories(s.id)
+	if err != nil {
+		return nil, fmt.Errorf("load memory failed: %w", err)
+	}
+	s.shared = newStoredMemory(store, s.id, SharedMemoryKey)
+	for _, name := range names {
+		memory, err := NewPersistentMemory(store, s.id, name)
+		if err != nil {
+			return nil, err
+		}
+		if name == SharedMemoryKey {
+			s.shared = memory
+			continue
+		}
+		s.memories[name] = memory
+	}
+	s.resetCursors()
+	return s, nil
+}
+
+// ID 返回会话ID
+func (s *Session) ID() string {
+	return s.id
+}
+
+// Memory 返回指定Agent在会话中的私有记忆，不存在时创建
+func (s *Session) Memory(agentName string) *Memory {
+	s.mu.RLock()
+	memory, ok := s.memories[agentName]
+	s.mu.RUnlock()
+	if ok {
+		return memory
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if memory, ok := s.memories[agentName]; ok {
+		return memory
+	}
+	memory = NewMemory()
+	if s.store != nil {
+		memory = newStoredMemory(s.store, s.id, agentName)
+	}
+	s.memories[agentName] = memory
+	return memory
+}
+
+// Shared 返回共享发言记录，每条消息的内容为 "[名称]: 内容"
+func (s *Session) Shared() *Memory {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.shared
+}
+
+// Blackboard 返回会话的黑板
+func (s *Session) Blackboard() *Blackboard {
+	return s.board
+}
+
+// SetRecorder 设置运行记录器，之后会话中Agent的消息、工具调用和轮次边界都会被记录
+func (s *Session) SetRecorder(r *Recorder) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.recorder = r
+}
+
+// Recorder 返回会话的运行记录器，未设置时返回nil
+func (s *Session) Recorder() *Recorder {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.recorder
+}
+
+// Post 以speaker的名义向共享发言记录写入一条消息
+func (s *Session) Post(speaker, content string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.shared.AddMessage(oneapi.ChatMessage{Role: "assistant", Content: attribute(speaker, content)})
+	s.total++
+}
+
+// unseen 返回agentName尚未看到的其他Agent的发言，并标记为已看到
+func (s *Session) unseen(agentName string) []oneapi.ChatMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cursor := s.cursors[agentName]
+	s.cursors[agentName] = s.total
+	if cursor >= s.total {
+		return nil
+	}
+
+	history := s.shared.GetHistory()
+	start := len(history) - (s.total - cursor)
+	if start < 0 {
+		start = 0
+	}
+	prefix := attribute(agentName, "")
+	var messages []oneapi.ChatMessage
+	for _, msg := range history[start:] {
+		if strings.HasPrefix(msg.Content, prefix) {
+			continue // 自己的发言已在私有记忆中
+		}
+		messages = append(messages, oneapi.ChatMessage{Role: "user", Content: msg.Content})
+	}
+	return messages
+}
+
+// attribute 为内容注明发言者
+func attribute(speaker, content string) string {
+	return fmt.Sprintf("[%s]: %s", speaker, content)
+}
+
+// SetMemoryStrategy 设置会话默认的记忆整理策略，Agent自身设置的策略优先
+func (s *Session) SetMemoryStrategy(strategy MemoryStrategy) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.strategy = strategy
+}
+
+// MemoryStrategy 返回会话默认的记忆整理策略，未设置时返回nil
+func (s *Session) MemoryStrategy() MemoryStrategy {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.strategy
+}
+
+// Reset 清空会话中的所有记忆、共享发言记录和黑板
+func (s *Session) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, memory := range s.memories {
+		memory.Clear()
+	}
+	s.shared.Clear()
+	s.total = 0
+	s.cursors = make(map[string]int)
+	s.board.Clear()
+}
+
+// Snapshot 获取各Agent的私有记忆和共享发言记录（键为 SharedMemoryKey），可用于保存会话或检查点
+func (s *Session) Snapshot(agents []Agent) map[string][]oneapi.ChatMessage {
+	memories := make(map[string][]oneapi.ChatMessage)
+	for _, a := range agents {
+		memories[a.Name()] = s.Memory(a.Name()).GetHistory()
+	}
+	memories[SharedMemoryKey] = s.Shared().GetHistory()
+	return memories
+}
+
+// Restore 将保存的对话记忆恢复到会话
+func (s *Session) Restore(agents []Agent, memories map[string][]oneapi.ChatMessage) {
+	for _, a := range agents {
+		if history, ok := memories[a.Name()]; ok {
+			s.Memory(a.Name()).Replace(history)
+		}
+	}
+	if shared, ok := memories[SharedMemoryKey]; ok {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.shared.Replace(shared)
+		s.resetCursors()
+	}
+}
+
+// snapshotAll 获取会话中全部Agent的私有记忆和共享发言记录
+func (s *Session) snapshotAll() map[string][]oneapi.ChatMessage {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	memories := make(map[string][]oneapi.ChatMessage, len(s.memories)+1)
+	for name, memory := range s.memories {
+		memories[name] = memory.GetHistory()
+	}
+	memories[SharedMemoryKey] = s.shared.GetHistory()
+	return memories
+}
+
+// restoreAll 恢复保存的全部记忆，不需要事先知道Agent列表
+func (s *Session) restoreAll(memories map[string][]oneapi.ChatMessage) {
+	for name, history := range memories {
+		if name != SharedMemoryKey {
+			s.Memory(name).Replace(history)
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if shared, ok := memories[SharedMemoryKey]; ok {
+		s.shared.Replace(shared)
+	}
+	s.resetCursors()
+}
+
+// resetCursors 根据各Agent的私有记忆推断它已经看到的共享发言：
+// 最后一条由它发出或已在它私有记忆中的发言之前的内容视为已看到。调用方需持有写锁
+func (s *Session) resetCursors() {
+	shared := s.shared.GetHistory()
+	s.total = len(shared)
+	s.cursors = make(map[string]int)
+	for name, memory := range s.memories {
+		seen := make(map[string]bool)
+		for _, msg := range memory.GetHistory() {
+			if msg.Role == "user" {
+				seen[msg.Content] = true
+			}
+		}
+		prefix := attribute(name, "")
+		for i := len(shared) - 1; i >= 0; i-- {
+			if strings.HasPrefix(shared[i].Content, prefix) || seen[shared[i].Content] {
+				s.cursors[name] = i + 1
+				break
+			}
+		}
+	}
+}
+
+type sessionKey struct{}
+
+// WithSession 返回携带会话的上下文
+func WithSession(ctx context.Context, s *Session) context.Context {
+	return context.WithValue(ctx, sessionKey{}, s)
+}
+
+// SessionFromContext 获取上下文中的会话，没有时返回nil
+func SessionFromContext(ctx context.Context) *Session {
+	s, _ := ctx.Value(sessionKey{}).(*Session)
+	return s
+}
+
+type agentNameKey struct{}
+
+// withAgentName 返回携带当前Agent名称的上下文，工具可据此得知调用者
+func withAgentName(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, agentNameKey{}, name)
+}
+
+// AgentNameFromContext 返回调用工具的Agent名称，不在Agent中调用时返回空字符串
+func AgentNameFromContext(ctx context.Context) string {
+	name, _ := ctx.Value(agentNameKey{}).(string)
+	return name
+}
+
+// sessionContext 上下文中没有会话时为本次执行创建新的会话
+func sessionContext(ctx context.Context) context.Context {
+	if SessionFromContext(ctx) != nil {
+		return ctx
+	}
+	return WithSession(ctx, NewSession(""))
+}
@@ -0,0 +1,47 @@
+package agent
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"sync"
+)
+
+// lineReader 按需从终端读取一行。只在有人等待时读取，因此可以与其他读取同一终端的组件共用；
+// 等待被取消时，已开始的读取结果留给下一次读取
+type lineReader struct {
+	in      *bufio.Reader
+	lines   chan lineResult
+	reading bool
+	mu      sync.Mutex // 同一时间只有一个等待者
+}
+
+type lineResult struct {
+	line string
+	err  error
+}
+
+func newLineReader(in io.Reader) *lineReader {
+	return &lineReader{in: bufio.NewReader(in), lines: make(chan lineResult, 1)}
+}
+
+// readLine 读取一行，调用方需持有 mu
+func (r *lineReader) readLine(ctx context.Context) (string, error) {
+	if !r.reading {
+		r.reading = true
+		go func() {
+			line, err := r.in.ReadString('\n')
+			if line != "" {
+				err = nil
+			}
+			r.lines <- lineResult{line, err}
+		}()
+	}
+	select {
+	case <-ctx.Done():
+		return "", ctx.Err()
+	case res := <-r.lines:
+		r.reading = false
+		return res.line, res.err
+	}
+}
@@ -0,0 +1,116 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"multi-agent/oneapi"
+	"multi-agent/tools"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	DefaultToolConcurrency   = 4           // 模型一次返回多个工具调用时最多同时执行的数量
+	DefaultToolTimeout       = time.Minute // 工具和Agent都未设置时的执行超时时间
+	DefaultMaxToolResultSize = 32 * 1024   // 工具和Agent都未设置时返回给模型的结果最大字节数
+)
+
+// toolOutcome 一次工具调用的结果
+type toolOutcome struct {
+	result string
+	err    error
+}
+
+// runToolCalls 并发执行模型一次返回的工具调用，最多同时执行limit个，结果按调用顺序返回
+func (e *ExpertAgent) runToolCalls(ctx context.Context, calls []oneapi.ToolCall, limit int) []toolOutcome {
+	outcomes := make([]toolOutcome, len(calls))
+	if len(calls) == 1 || limit <= 1 {
+		for i, call := range calls {
+			outcomes[i].result, outcomes[i].err = e.executeToolCall(ctx, call)
+		}
+		return outcomes
+	}
+
+	sem := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+	for i, call := range calls {
+		wg.Add(1)
+		go func(i int, call oneapi.ToolCall) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			outcomes[i].result, outcomes[i].err = e.executeToolCall(ctx, call)
+		}(i, call)
+	}
+	wg.Wait()
+	return outcomes
+}
+
+// SetToolResourceLimits 覆盖工具的执行限制，优先于工具自身声明的限制（tools.LimitedTool）。
+// name为空时作用于该Agent的所有工具，指定名称的设置优先；字段为0时沿用下一级的设置，小于0表示不限制
+func (e *ExpertAgent) SetToolResourceLimits(name string, limits tools.Limits) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.resources == nil {
+		e.resources = make(map[string]tools.Limits)
+	}
+	e.resources[name] = limits
+}
+
+// resourceLimitsFor 返回工具的执行限制：Agent对该工具的设置、Agent对所有工具的设置、工具声明的限制、默认值，依次优先
+func (e *ExpertAgent) resourceLimitsFor(tool tools.Tool) tools.Limits {
+	limits := tools.Limits{Timeout: DefaultToolTimeout, MaxResultSize: DefaultMaxToolResultSize}
+	if lt, ok := tool.(tools.LimitedTool); ok {
+		limits = limits.Merge(lt.GetLimits())
+	}
+	e.mu.Lock()
+	limits = limits.Merge(e.resources[""]).Merge(e.resources[tool.GetName()])
+	e.mu.Unlock()
+	return limits
+}
+
+// executeWithTimeout 在超时时间内执行工具。工具未响应上下文取消时不再等待它返回，
+// 执行它的goroutine会在工具返回后结束
+func executeWithTimeout(ctx context.Context, tool tools.Tool, params map[string]interface{}, timeout time.Duration) (interface{}, error) {
+	if timeout <= 0 {
+		return tool.Execute(ctx, params)
+	}
+	parent := ctx
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	type result struct {
+		value interface{}
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		value, err := tool.Execute(ctx, params)
+		done <- result{value, err}
+	}()
+
+	var r result
+	select {
+	case r = <-done:
+	case <-ctx.Done():
+		r.err = ctx.Err()
+	}
+	if r.err != nil && parent.Err() == nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return nil, &toolCallError{Kind: ToolErrorTimeout, Tool: tool.GetName(), Err: fmt.Errorf("工具执行超时（%s）", timeout)}
+	}
+	return r.value, r.err
+}
+
+// truncateResult 将超过max字节的结果截断，并附加说明告知模型
+func truncateResult(result string, max int) string {
+	if max <= 0 || len(result) <= max {
+		return result
+	}
+	cut := max
+	for cut > 0 && !utf8.RuneStart(result[cut]) {
+		cut--
+	}
+	return result[:cut] + fmt.Sprintf("\n\n[结果过长已截断：共 %d 字节，仅保留前 %d 字节。如需其余内容，请缩小查询范围后重新调用]", len(result), cut)
+}
@@ -0,0 +1,224 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"multi-agent/oneapi"
+	"multi-agent/tools"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// scriptedLLM 按顺序返回预设的助手消息并记录收到的请求，预设消息用完后回复 "done"
+type scriptedLLM struct {
+	mu       sync.Mutex
+	replies  []oneapi.ChatMessage
+	requests []oneapi.ChatCompletionRequest
+}
+
+func (s *scriptedLLM) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req oneapi.ChatCompletionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.mu.Lock()
+	s.requests = append(s.requests, req)
+	reply := oneapi.ChatMessage{Role: "assistant", Content: "done"}
+	if len(s.replies) > 0 {
+		reply, s.replies = s.replies[0], s.replies[1:]
+	}
+	s.mu.Unlock()
+	json.NewEncoder(w).Encode(oneapi.ChatCompletionResponse{
+		ID:      "chatcmpl-test",
+		Object:  "chat.completion",
+		Model:   req.Model,
+		Choices: []oneapi.Choice{{Message: reply}},
+	})
+}
+
+// lastRequest 返回最后一次请求
+func (s *scriptedLLM) lastRequest() oneapi.ChatCompletionRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[len(s.requests)-1]
+}
+
+// callTools 返回调用工具的助手消息
+func callTools(calls ...oneapi.ToolCall) oneapi.ChatMessage {
+	return oneapi.ChatMessage{Role: "assistant", ToolCalls: calls}
+}
+
+func toolCall(id, name, arguments string) oneapi.ToolCall {
+	call := oneapi.ToolCall{ID: id, Type: "function"}
+	call.Function.Name = name
+	call.Function.Arguments = arguments
+	return call
+}
+
+// funcTool 由函数实现的测试工具，参数 x 为必需参数
+type funcTool struct {
+	name string
+	fn   func(ctx context.Context, params map[string]interface{}) (interface{}, error)
+}
+
+func (f *funcTool) Execute(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+	return f.fn(ctx, params)
+}
+
+func (f *funcTool) GetDescription() string { return "测试工具 " + f.name }
+func (f *funcTool) GetName() string        { return f.name }
+
+func (f *funcTool) GetParameters() map[string]tools.ParameterSpec {
+	return map[string]tools.ParameterSpec{
+		"x": {Type: "string", Description: "参数", Required: true},
+	}
+}
+
+// echoTool 返回参数 x 的工具
+func echoTool(name string) *funcTool {
+	return &funcTool{name: name, fn: func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+		return params["x"], nil
+	}}
+}
+
+// newToolAgent 创建使用脚本化模型和指定工具的Agent
+func newToolAgent(t *testing.T, llm *scriptedLLM, toolList ...tools.Tool) *ExpertAgent {
+	t.Helper()
+	useFakeLLM(t, llm)
+	a := NewAgent("worker", "测试", "负责调用工具")
+	for _, tool := range toolList {
+		a.AddTool(tool)
+	}
+	return a
+}
+
+// toolMessages 返回请求中的 tool 消息
+func toolMessages(req oneapi.ChatCompletionRequest) []oneapi.ChatMessage {
+	var messages []oneapi.ChatMessage
+	for _, msg := range req.Messages {
+		if msg.Role == "tool" {
+			messages = append(messages, msg)
+		}
+	}
+	return messages
+}
+
+// toolErrorType 解析工具错误结果中的错误类型，不是错误结果时返回空
+func toolErrorType(content string) string {
+	var result struct {
+		Type string `json:"type"`
+	}
+	json.Unmarshal([]byte(content), &result)
+	return result.Type
+}
+
+func TestParallelToolCallsKeepOrder(t *testing.T) {
+	// 先发起的调用耗时最长，结果仍按调用顺序返回
+	var inFlight, maxSeen int32
+	slow := &funcTool{name: "slow", fn: func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			seen := atomic.LoadInt32(&maxSeen)
+			if n <= seen || atomic.CompareAndSwapInt32(&maxSeen, seen, n) {
+				break
+			}
+		}
+		delay, _ := time.ParseDuration(params["x"].(string))
+		time.Sleep(delay)
+		return params["x"], nil
+	}}
+	llm := &scriptedLLM{replies: []oneapi.ChatMessage{callTools(
+		toolCall("call_1", "slow", `{"x": "60ms"}`),
+		toolCall("call_2", "slow", `{"x": "30ms"}`),
+		toolCall("call_3", "missing", `{"x": "0s"}`),
+		toolCall("call_4", "slow", `{"x": "1ms"}`),
+	)}}
+	a := newToolAgent(t, llm, slow)
+	a.SetToolConcurrency(2)
+
+	if _, err := a.Execute(context.Background(), "开始"); err != nil {
+		t.Fatal(err)
+	}
+	last := llm.lastRequest()
+	// 一条包含全部调用的 assistant 消息，其后按调用顺序跟随对应的 tool 消息
+	messages := last.Messages[2:]
+	if len(messages) != 5 || len(messages[0].ToolCalls) != 4 {
+		t.Fatalf("got %d messages after the input, want one call and 4 results: %+v", len(messages), messages)
+	}
+	for i, want := range []string{"60ms", "30ms", ToolErrorNotFound, "1ms"} {
+		got := messages[i+1]
+		if got.Role != "tool" || got.ToolCallID != messages[0].ToolCalls[i].ID || !strings.Contains(got.Content, want) {
+			t.Errorf("result %d = %+v, want %s for %s", i, got, want, messages[0].ToolCalls[i].ID)
+		}
+	}
+	if got := atomic.LoadInt32(&maxSeen); got != 2 {
+		t.Errorf("max concurrent tool calls = %d, want 2", got)
+	}
+}
+
+func TestToolTimeout(t *testing.T) {
+	blocking := &funcTool{name: "block", fn: func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}}
+	llm := &scriptedLLM{replies: []oneapi.ChatMessage{callTools(toolCall("call_1", "block", `{"x": "a"}`))}}
+	a := newToolAgent(t, llm, blocking)
+	a.SetToolResourceLimits("block", tools.Limits{Timeout: 20 * time.Millisecond})
+
+	if _, err := a.Execute(context.Background(), "开始"); err != nil {
+		t.Fatal(err)
+	}
+	results := toolMessages(llm.lastRequest())
+	if len(results) != 1 || toolErrorType(results[0].Content) != ToolErrorTimeout {
+		t.Fatalf("results = %+v, want a timeout error", results)
+	}
+}
+
+func TestToolCancelled(t *testing.T) {
+	// 运行被取消时不再把结果返回给模型
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	blocking := &funcTool{name: "block", fn: func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+		cancel()
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}}
+	llm := &scriptedLLM{replies: []oneapi.ChatMessage{callTools(toolCall("call_1", "block", `{"x": "a"}`))}}
+	a := newToolAgent(t, llm, blocking)
+
+	if _, err := a.Execute(ctx, "开始"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v, want context.Canceled", err)
+	}
+	if len(llm.requests) != 1 {
+		t.Errorf("got %d requests, want 1", len(llm.requests))
+	}
+}
+
+func TestToolResultTruncated(t *testing.T) {
+	long := &funcTool{name: "long", fn: func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+		return strings.Repeat("数据", 20), nil
+	}}
+	llm := &scriptedLLM{replies: []oneapi.ChatMessage{callTools(toolCall("call_1", "long", `{"x": "a"}`))}}
+	a := newToolAgent(t, llm, long)
+	a.SetToolResourceLimits("", tools.Limits{MaxResultSize: 16})
+
+	if _, err := a.Execute(context.Background(), "开始"); err != nil {
+		t.Fatal(err)
+	}
+	results := toolMessages(llm.lastRequest())
+	if len(results) != 1 {
+		t.Fatalf("got %d tool results, want 1", len(results))
+	}
+	// 在字符边界截断：16字节中只能完整保留5个3字节的字符
+	want := strings.Repeat("数据", 2) + "数\n\n[结果过长已截断：共 120 字节，仅保留前 15 字节。"
+	if !strings.HasPrefix(results[0].Content, want) {
+		t.Errorf("result = %q, want prefix %q", results[0].Content, want)
+	}
+}
@@ -0,0 +1,49 @@
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"multi-agent/oneapi"
+)
+
+// DefaultMaxToolFailures 连续失败多少次工具调用后终止执行
+const DefaultMaxToolFailures = 3
+
+// 返回给模型的工具调用错误类型
+const (
+	ToolErrorNotFound        = "tool_not_found"    // 工具不存在
+	ToolErrorInvalidArgument = "invalid_arguments" // 参数不是合法的JSON对象或缺少必需参数
+	ToolErrorExecution       = "execution_failed"  // 工具执行失败
+	ToolErrorTimeout         = "timeout"           // 工具执行超时
+	ToolErrorDenied          = "denied"            // 调用未获人工批准
+)
+
+// toolCallError 工具调用失败的原因，以 tool 消息返回给模型，让模型修正参数或改用其他方式
+type toolCallError struct {
+	Kind string
+	Tool string
+	Err  error
+}
+
+func (e *toolCallError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *toolCallError) Unwrap() error {
+	return e.Err
+}
+
+// toolErrorResult 将工具调用错误转换为返回给模型的JSON
+func toolErrorResult(call oneapi.ToolCall, err error) string {
+	kind := ToolErrorExecution
+	var te *toolCallError
+	if errors.As(err, &te) {
+		kind, err = te.Kind, te.Err
+	}
+	data, _ := json.Marshal(map[string]string{
+		"error": err.Error(),
+		"type":  kind,
+		"tool":  call.Function.Name,
+	})
+	return string(data)
+}
@@ -0,0 +1,77 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestToolErrorsReturnedToModel(t *testing.T) {
+	failing := &funcTool{name: "fail", fn: func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+		return nil, errors.New("磁盘已满")
+	}}
+	llm := &scriptedLLM{}
+	llm.replies = append(llm.replies,
+		callTools(toolCall("call_1", "echo", `{}`)),
+		callTools(toolCall("call_2", "missing", `{"x": "a"}`)),
+		callTools(toolCall("call_3", "echo", `not json`)),
+		callTools(toolCall("call_4", "echo", `{"x": "ok"}`)),
+		callTools(toolCall("call_5", "fail", `{"x": "a"}`)),
+	)
+	a := newToolAgent(t, llm, echoTool("echo"), failing)
+	a.SetMaxToolFailures(4)
+
+	output, err := a.Execute(context.Background(), "开始")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(output, "done") {
+		t.Errorf("output %q does not end with the final answer", output)
+	}
+
+	results := toolMessages(llm.lastRequest())
+	want := []struct {
+		id, kind, content string
+	}{
+		{"call_1", ToolErrorInvalidArgument, "缺少必需参数: x"},
+		{"call_2", ToolErrorNotFound, "未找到工具: missing"},
+		{"call_3", ToolErrorInvalidArgument, "参数解析失败"},
+		{"call_4", "", "ok"},
+		{"call_5", ToolErrorExecution, "磁盘已满"},
+	}
+	if len(results) != len(want) {
+		t.Fatalf("got %d tool results, want %d", len(results), len(want))
+	}
+	for i, w := range want {
+		got := results[i]
+		if got.ToolCallID != w.id || toolErrorType(got.Content) != w.kind || !strings.Contains(got.Content, w.content) {
+			t.Errorf("result %d = %+v, want %s %q containing %q", i, got, w.id, w.kind, w.content)
+		}
+	}
+}
+
+func TestToolConsecutiveFailureLimit(t *testing.T) {
+	// 成功的调用重置连续失败次数，之后连续失败两次时终止
+	llm := &scriptedLLM{}
+	llm.replies = append(llm.replies,
+		callTools(toolCall("call_1", "echo", `{}`)),
+		callTools(toolCall("call_2", "echo", `{"x": "ok"}`)),
+		callTools(toolCall("call_3", "echo", `{"y": 1}`)),
+		callTools(toolCall("call_4", "echo", `{"y": 2}`)),
+	)
+	a := newToolAgent(t, llm, echoTool("echo"))
+	a.SetMaxToolFailures(2)
+
+	_, err := a.Execute(context.Background(), "开始")
+	if !errors.Is(err, ErrToolCallFailed) || !strings.Contains(err.Error(), "连续 2 次失败") {
+		t.Fatalf("got %v, want ErrToolCallFailed after 2 consecutive failures", err)
+	}
+	if len(llm.requests) != 4 {
+		t.Errorf("got %d requests, want 4", len(llm.requests))
+	}
+	var te *toolCallError
+	if !errors.As(err, &te) || te.Kind != ToolErrorInvalidArgument {
+		t.Errorf("error does not wrap the last tool error: %v", err)
+	}
+}
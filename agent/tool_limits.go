@@ -0,0 +1,69 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"multi-agent/oneapi"
+)
+
+// ToolLimitAction 工具调用超出限制时的处理方式
+type ToolLimitAction int
+
+const (
+	ForceFinalAnswer ToolLimitAction = iota // 以 tool_choice: none 要求模型根据已有结果直接回答（默认）
+	FailOnToolLimit                         // 返回 ErrToolLimitExceeded
+)
+
+const (
+	DefaultMaxToolRounds    = 10 // 一次执行中最多的工具调用轮数
+	DefaultMaxRepeatedCalls = 3  // 工具和参数都相同的调用最多执行的次数
+)
+
+// toolLimits 一次执行的工具调用限制
+type toolLimits struct {
+	maxRounds   int
+	maxRepeats  int
+	maxFailures int // 连续失败多少次后终止执行
+	concurrency int // 同时执行的工具调用数
+	action      ToolLimitAction
+}
+
+// toolGuard 统计一次执行中的工具调用，检测超出轮数和重复调用
+type toolGuard struct {
+	limits toolLimits
+	rounds int
+	calls  map[string]int // 工具名称和规范化参数到调用次数
+}
+
+func newToolGuard(limits toolLimits) *toolGuard {
+	return &toolGuard{limits: limits, calls: make(map[string]int)}
+}
+
+// check 记录模型新一轮返回的工具调用，超出限制时返回原因
+func (g *toolGuard) check(toolCalls []oneapi.ToolCall) string {
+	g.rounds++
+	if g.rounds > g.limits.maxRounds {
+		return fmt.Sprintf("超过最大工具调用轮数 %d", g.limits.maxRounds)
+	}
+	for _, call := range toolCalls {
+		key := call.Function.Name + canonicalArguments(call.Function.Arguments)
+		g.calls[key]++
+		if g.calls[key] > g.limits.maxRepeats {
+			return fmt.Sprintf("以相同参数重复调用 %s %d 次", call.Function.Name, g.calls[key])
+		}
+	}
+	return ""
+}
+
+// canonicalArguments 规范化JSON参数，使键顺序和空白不同的相同参数被视为同一调用
+func canonicalArguments(arguments string) string {
+	var v interface{}
+	if err := json.Unmarshal([]byte(arguments), &v); err != nil {
+		return arguments
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return arguments
+	}
+	return string(data)
+}
@@ -0,0 +1,80 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestToolLimitForceFinalAnswer(t *testing.T) {
+	// 以相同参数调用三次后第四次超出重复限制
+	llm := &scriptedLLM{}
+	for i := 0; i < 4; i++ {
+		llm.replies = append(llm.replies, callTools(toolCall(fmt.Sprintf("call_%d", i), "echo", `{"x": "a"}`)))
+	}
+	a := newToolAgent(t, llm, echoTool("echo"))
+
+	output, err := a.Execute(context.Background(), "开始")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(output, "done") {
+		t.Errorf("output %q does not end with the final answer", output)
+	}
+	if len(llm.requests) != 5 {
+		t.Fatalf("got %d requests, want 5", len(llm.requests))
+	}
+	last := llm.lastRequest()
+	if last.ToolChoice != "none" {
+		t.Errorf("tool_choice = %v, want none", last.ToolChoice)
+	}
+	notice := last.Messages[len(last.Messages)-1]
+	if notice.Role != "system" || !strings.Contains(notice.Content, "以相同参数重复调用 echo 4 次") {
+		t.Errorf("last message = %+v, want the limit notice", notice)
+	}
+	// 超出限制的调用没有执行，之前的每次调用都跟随对应的结果
+	var roles []string
+	for _, msg := range last.Messages {
+		roles = append(roles, msg.Role)
+	}
+	if got := strings.Join(roles, ","); got != "system,user,assistant,tool,assistant,tool,assistant,tool,system" {
+		t.Errorf("messages = %s", got)
+	}
+}
+
+func TestToolLimitFail(t *testing.T) {
+	llm := &scriptedLLM{}
+	for i := 0; i < 3; i++ {
+		llm.replies = append(llm.replies, callTools(toolCall(fmt.Sprintf("call_%d", i), "echo", fmt.Sprintf(`{"x": "%d"}`, i))))
+	}
+	a := newToolAgent(t, llm, echoTool("echo"))
+	a.SetToolLimits(2, 0, FailOnToolLimit)
+
+	_, err := a.Execute(context.Background(), "开始")
+	if !errors.Is(err, ErrToolLimitExceeded) || !strings.Contains(err.Error(), "超过最大工具调用轮数 2") {
+		t.Fatalf("got %v, want ErrToolLimitExceeded after 2 rounds", err)
+	}
+	if len(llm.requests) != 3 {
+		t.Errorf("got %d requests, want 3", len(llm.requests))
+	}
+}
+
+func TestToolLimitModelIgnoresToolChoice(t *testing.T) {
+	// 要求直接回答后模型仍调用工具时返回错误
+	llm := &scriptedLLM{}
+	for i := 0; i < 3; i++ {
+		llm.replies = append(llm.replies, callTools(toolCall(fmt.Sprintf("call_%d", i), "echo", fmt.Sprintf(`{"x": "%d"}`, i))))
+	}
+	a := newToolAgent(t, llm, echoTool("echo"))
+	a.SetToolLimits(1, 0, ForceFinalAnswer)
+
+	_, err := a.Execute(context.Background(), "开始")
+	if !errors.Is(err, ErrToolLimitExceeded) {
+		t.Fatalf("got %v, want ErrToolLimitExceeded", err)
+	}
+	if got := llm.lastRequest().ToolChoice; got != "none" {
+		t.Errorf("tool_choice = %v, want none", got)
+	}
+}
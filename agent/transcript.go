@@ -0,0 +1,304 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"multi-agent/oneapi"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// TranscriptVersion 当前的运行记录格式版本
+//
+// 版本0为早期 ambergen run --json 的输出，只包含各轮结果，读取时自动兼容。
+const TranscriptVersion = 1
+
+// 运行记录事件类型
+const (
+	TranscriptMessage  = "message"        // Agent对话中的一条消息，包括输入、工具调用、工具结果和回复
+	TranscriptError    = "error"          // Agent执行失败
+	TranscriptRoundEnd = "round_complete" // 一轮结束
+)
+
+// Transcript 一次运行的完整记录，可保存为JSON，之后加载到会话中继续对话或比较两次运行
+type Transcript struct {
+	Version    int                             `json:"version"`
+	RunID      string                          `json:"run_id"`
+	SessionID  string                          `json:"session_id,omitempty"`
+	Workflow   string                          `json:"workflow,omitempty"`
+	Input      string                          `json:"input"`
+	StartedAt  time.Time                       `json:"started_at,omitempty"`
+	FinishedAt time.Time                       `json:"finished_at,omitempty"`
+	Agents     []TranscriptAgent               `json:"agents,omitempty"`     // 参与的Agent及其系统提示词
+	Events     []TranscriptEvent               `json:"events,omitempty"`     // 按发生顺序记录的消息和轮次边界
+	Rounds     []RoundResult                   `json:"rounds"`               // 各轮结果
+	Memories   map[string][]oneapi.ChatMessage `json:"memories,omitempty"`   // 结束时各Agent的私有记忆和共享发言记录
+	Blackboard []BlackboardEntry               `json:"blackboard,omitempty"` // 结束时的黑板内容
+}
+
+// TranscriptAgent 运行记录中的Agent信息
+type TranscriptAgent struct {
+	Name         string   `json:"name"`
+	Expertise    string   `json:"expertise,omitempty"`
+	Model        string   `json:"model,omitempty"`
+	SystemPrompt string   `json:"system_prompt"`
+	Tools        []string `json:"tools,omitempty"`
+}
+
+// TranscriptEvent 运行记录中的一个事件
+type TranscriptEvent struct {
+	Seq     int                 `json:"seq"`
+	Type    string              `json:"type"`
+	Time    time.Time           `json:"time"`
+	Round   int                 `json:"round"` // 轮次，从1开始
+	Agent   string              `json:"agent,omitempty"`
+	Message *oneapi.ChatMessage `json:"message,omitempty"`
+	Error   string              `json:"error,omitempty"`
+}
+
+// ParseTranscript 解析运行记录，兼容旧版本，不支持更新的版本
+func ParseTranscript(data []byte) (*Transcript, error) {
+	var t Transcript
+	if err := json.Unmarshal(data, &t); err != nil {
+		return nil, err
+	}
+	if t.Version > TranscriptVersion {
+		return nil, fmt.Errorf("%w: version %d, supported up to %d", ErrUnsupportedTranscript, t.Version, TranscriptVersion)
+	}
+	return &t, nil
+}
+
+// ReadTranscript 从文件读取运行记录
+func ReadTranscript(path string) (*Transcript, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read transcript failed: %w", err)
+	}
+	t, err := ParseTranscript(data)
+	if err != nil {
+		return nil, fmt.Errorf("parse transcript %s failed: %w", path, err)
+	}
+	return t, nil
+}
+
+// WriteFile 将运行记录写入文件
+func (t *Transcript) WriteFile(path string) error {
+	data, err := json.MarshalIndent(t, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal transcript failed: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("write transcript failed: %w", err)
+	}
+	return nil
+}
+
+// Restore 将记录中的记忆和黑板恢复到会话，之后可以在该会话中继续对话
+func (t *Transcript) Restore(s *Session) {
+	s.restoreAll(t.Memories)
+	s.Blackboard().Restore(t.Blackboard)
+}
+
+// AgentEvents 返回指定Agent的消息事件
+func (t *Transcript) AgentEvents(name string) []TranscriptEvent {
+	var events []TranscriptEvent
+	for _, e := range t.Events {
+		if e.Agent == name {
+			events = append(events, e)
+		}
+	}
+	return events
+}
+
+// Recorder 记录运行过程，设置到会话后（Session.SetRecorder）由Agent和工作流自动写入
+//
+// 未设置记录器时内部方法可以在nil上调用，不做任何记录。
+type Recorder struct {
+	transcript Transcript
+	agents     map[string]int // Agent名称到 transcript.Agents 下标
+	mu         sync.Mutex
+}
+
+// NewRecorder 创建运行记录器
+func NewRecorder() *Recorder {
+	return &Recorder{
+		transcript: Transcript{Version: TranscriptVersion, StartedAt: time.Now()},
+		agents:     make(map[string]int),
+	}
+}
+
+// Transcript 返回当前的运行记录，s不为nil时附带会话中的记忆和黑板
+func (r *Recorder) Transcript(s *Session) *Transcript {
+	r.mu.Lock()
+	t := r.transcript
+	t.Agents = append([]TranscriptAgent(nil), t.Agents...)
+	t.Events = append([]TranscriptEvent(nil), t.Events...)
+	t.Rounds = append([]RoundResult(nil), t.Rounds...)
+	r.mu.Unlock()
+
+	t.FinishedAt = time.Now()
+	if s != nil {
+		t.SessionID = s.ID()
+		t.Memories = s.snapshotAll()
+		t.Blackboard = s.Blackboard().List("")
+	}
+	return &t
+}
+
+// SetWorkflow 设置记录中的工作流名称
+func (r *Recorder) SetWorkflow(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.transcript.Workflow = name
+}
+
+// begin 记录运行ID和输入，恢复运行时带入已完成的轮次
+func (r *Recorder) begin(runID, input string, rounds []RoundResult) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.transcript.RunID = runID
+	r.transcript.Input = input
+	if len(r.transcript.Rounds) == 0 {
+		r.transcript.Rounds = append(r.transcript.Rounds, rounds...)
+	}
+}
+
+// agent 记录Agent信息，系统提示词变化时更新
+func (r *Recorder) agent(info TranscriptAgent) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if i, ok := r.agents[info.Name]; ok {
+		r.transcript.Agents[i] = info
+		return
+	}
+	r.agents[info.Name] = len(r.transcript.Agents)
+	r.transcript.Agents = append(r.transcript.Agents, info)
+}
+
+// message 记录Agent对话中的一条消息
+func (r *Recorder) message(agentName string, msg oneapi.ChatMessage) {
+	if r == nil {
+		return
+	}
+	msg = copyMessage(msg)
+	r.add(TranscriptEvent{Type: TranscriptMessage, Agent: agentName, Message: &msg})
+}
+
+// fail 记录Agent执行失败
+func (r *Recorder) fail(agentName string, err error) {
+	if r == nil {
+		return
+	}
+	r.add(TranscriptEvent{Type: TranscriptError, Agent: agentName, Error: err.Error()})
+}
+
+// completeRound 记录一轮结束
+func (r *Recorder) completeRound(results RoundResult) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	r.transcript.Rounds = append(r.transcript.Rounds, results)
+	r.mu.Unlock()
+	r.add(TranscriptEvent{Type: TranscriptRoundEnd})
+}
+
+func (r *Recorder) add(event TranscriptEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	event.Seq = len(r.transcript.Events) + 1
+	event.Time = time.Now()
+	event.Round = len(r.transcript.Rounds) + 1
+	if event.Type == TranscriptRoundEnd {
+		event.Round--
+	}
+	r.transcript.Events = append(r.transcript.Events, event)
+}
+
+// TranscriptDiff 两次运行的一处差异
+type TranscriptDiff struct {
+	Round int    `json:"round"` // 轮次，从1开始
+	Agent string `json:"agent"`
+	Field string `json:"field"` // status、content、error 或 tool_calls
+	A     string `json:"a"`
+	B     string `json:"b"`
+}
+
+// DiffTranscripts 按轮次和Agent比较两次运行的结果和工具调用
+func DiffTranscripts(a, b *Transcript) []TranscriptDiff {
+	var diffs []TranscriptDiff
+	rounds := len(a.Rounds)
+	if len(b.Rounds) > rounds {
+		rounds = len(b.Rounds)
+	}
+	for i := 0; i < rounds; i++ {
+		ra, rb := roundAt(a, i), roundAt(b, i)
+		toolsA, toolsB := toolCallsByAgent(a, i+1), toolCallsByAgent(b, i+1)
+		for _, name := range unionNames(ra, rb) {
+			resultA, resultB := ra[name], rb[name]
+			add := func(field, va, vb string) {
+				if va != vb {
+					diffs = append(diffs, TranscriptDiff{Round: i + 1, Agent: name, Field: field, A: va, B: vb})
+				}
+			}
+			add("status", resultStatus(resultA), resultStatus(resultB))
+			if resultA != nil && resultB != nil {
+				add("content", resultA.Content, resultB.Content)
+				add("error", resultA.Error, resultB.Error)
+			}
+			add("tool_calls", toolsA[name], toolsB[name])
+		}
+	}
+	return diffs
+}
+
+func roundAt(t *Transcript, i int) RoundResult {
+	if i < len(t.Rounds) {
+		return t.Rounds[i]
+	}
+	return RoundResult{}
+}
+
+func resultStatus(r *AgentResult) string {
+	if r == nil {
+		return "absent"
+	}
+	return string(r.Status)
+}
+
+// toolCallsByAgent 返回指定轮次中各Agent的工具调用，每行一个
+func toolCallsByAgent(t *Transcript, round int) map[string]string {
+	calls := make(map[string]string)
+	for _, e := range t.Events {
+		if e.Round != round || e.Message == nil {
+			continue
+		}
+		for _, call := range e.Message.ToolCalls {
+			calls[e.Agent] += fmt.Sprintf("%s(%s)\n", call.Function.Name, call.Function.Arguments)
+		}
+	}
+	return calls
+}
+
+func unionNames(a, b RoundResult) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, r := range []RoundResult{a, b} {
+		for name := range r {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
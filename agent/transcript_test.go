@@ -0,0 +1,136 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"multi-agent/oneapi"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestTranscriptRoundTrip(t *testing.T) {
+	useFakeLLM(t, &fakeLLM{})
+	a := NewAgent("a", "领域a", "专家a")
+	// 串行执行，共享发言的顺序与各Agent看到它们的顺序一致，恢复时可以准确推断
+	g := NewGroup(2, false, nil)
+	g.AddAgent(a)
+	g.AddAgent(NewAgent("b", "领域b", "专家b"))
+
+	// 记录
+	session := NewSession("recorded")
+	recorder := NewRecorder()
+	recorder.SetWorkflow("team")
+	session.SetRecorder(recorder)
+	rounds, err := g.ExecuteDetailed(WithSession(context.Background(), session), "主题")
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorded := recorder.Transcript(session)
+	if recorded.Version != TranscriptVersion || recorded.RunID != g.RunID() || recorded.Input != "主题" {
+		t.Errorf("unexpected header: %+v", recorded)
+	}
+	if len(recorded.Rounds) != len(rounds) || len(recorded.Agents) != 2 {
+		t.Fatalf("recorded %d rounds and %d agents, want %d and 2", len(recorded.Rounds), len(recorded.Agents), len(rounds))
+	}
+	if len(recorded.AgentEvents("a")) == 0 || len(recorded.AgentEvents("b")) == 0 {
+		t.Fatal("transcript has no messages")
+	}
+
+	// 写入并读取
+	path := filepath.Join(t.TempDir(), "run.json")
+	if err := recorded.WriteFile(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := ReadTranscript(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.RunID != recorded.RunID || loaded.Workflow != "team" || len(loaded.Events) != len(recorded.Events) {
+		t.Errorf("loaded transcript differs: %+v", loaded)
+	}
+	if diffs := DiffTranscripts(recorded, loaded); len(diffs) != 0 {
+		t.Errorf("round trip changed results: %+v", diffs)
+	}
+	for i, e := range loaded.Events {
+		if want := recorded.Events[i]; e.Seq != want.Seq || e.Round != want.Round || e.Agent != want.Agent ||
+			!reflect.DeepEqual(e.Message, want.Message) {
+			t.Errorf("event %d = %+v, want %+v", i, e, want)
+		}
+	}
+
+	// 回放到新的会话后继续对话
+	replayed := NewSession("replayed")
+	loaded.Restore(replayed)
+	for _, name := range []string{"a", "b", SharedMemoryKey} {
+		if got, want := replayed.snapshotAll()[name], session.snapshotAll()[name]; !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: restored memory has %d messages, want %d", name, len(got), len(want))
+		}
+	}
+	// 在回放的会话中继续与在原会话中继续结果相同，未看到的共享发言不丢失也不重复
+	for _, s := range []*Session{session, replayed} {
+		if _, err := a.Execute(WithSession(context.Background(), s), "继续"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, want := replayed.Memory("a").GetHistory(), session.Memory("a").GetHistory(); !reflect.DeepEqual(got, want) {
+		t.Errorf("continued memory has %d messages, want %d", len(got), len(want))
+	}
+}
+
+func TestParseTranscriptVersions(t *testing.T) {
+	// 版本0是早期只包含各轮结果的输出
+	legacy, err := ParseTranscript([]byte(`{"input": "主题", "rounds": [{"a": {"status": "success", "content": "x"}}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if legacy.Version != 0 || legacy.Rounds[0]["a"].Content != "x" {
+		t.Errorf("unexpected legacy transcript: %+v", legacy)
+	}
+
+	_, err = ParseTranscript([]byte(`{"version": 2, "input": "主题", "rounds": []}`))
+	if !errors.Is(err, ErrUnsupportedTranscript) {
+		t.Errorf("got %v, want ErrUnsupportedTranscript", err)
+	}
+}
+
+func TestDiffTranscripts(t *testing.T) {
+	call := func(args string) *oneapi.ChatMessage {
+		c := oneapi.ToolCall{ID: "call_1", Type: "function"}
+		c.Function.Name = "calculator"
+		c.Function.Arguments = args
+		return &oneapi.ChatMessage{Role: "assistant", ToolCalls: []oneapi.ToolCall{c}}
+	}
+	a := &Transcript{
+		Rounds: []RoundResult{{
+			"writer":   {Status: StatusSuccess, Content: "3"},
+			"reviewer": {Status: StatusSuccess, Content: "通过"},
+		}},
+		Events: []TranscriptEvent{{Type: TranscriptMessage, Round: 1, Agent: "writer", Message: call(`{"a":1,"b":2}`)}},
+	}
+	b := &Transcript{
+		Rounds: []RoundResult{
+			{
+				"writer":   {Status: StatusSuccess, Content: "4"},
+				"reviewer": {Status: StatusFailed, Error: "timeout"},
+			},
+			{"writer": {Status: StatusSuccess, Content: "5"}},
+		},
+		Events: []TranscriptEvent{{Type: TranscriptMessage, Round: 1, Agent: "writer", Message: call(`{"a":2,"b":2}`)}},
+	}
+
+	want := []TranscriptDiff{
+		{Round: 1, Agent: "reviewer", Field: "status", A: "success", B: "failed"},
+		{Round: 1, Agent: "reviewer", Field: "content", A: "通过", B: ""},
+		{Round: 1, Agent: "reviewer", Field: "error", A: "", B: "timeout"},
+		{Round: 1, Agent: "writer", Field: "content", A: "3", B: "4"},
+		{Round: 1, Agent: "writer", Field: "tool_calls", A: "calculator({\"a\":1,\"b\":2})\n", B: "calculator({\"a\":2,\"b\":2})\n"},
+		{Round: 2, Agent: "writer", Field: "status", A: "absent", B: "success"},
+	}
+	if got := DiffTranscripts(a, b); !reflect.DeepEqual(got, want) {
+		t.Errorf("DiffTranscripts =\n%+v\nwant\n%+v", got, want)
+	}
+	if got := DiffTranscripts(a, a); len(got) != 0 {
+		t.Errorf("identical transcripts differ: %+v", got)
+	}
+}
@@ -0,0 +1,158 @@
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryItem 长期记忆中的一条内容
+type MemoryItem struct {
+	ID     string    `json:"id"`
+	Text   string    `json:"text"`
+	Agent  string    `json:"agent,omitempty"` // 产生该记忆的Agent
+	Task   string    `json:"task,omitempty"`  // 产生该记忆的会话ID
+	Time   time.Time `json:"time"`
+	Vector []float32 `json:"vector"`
+}
+
+// RecallFilter 召回时的元数据过滤条件，零值字段不参与过滤
+type RecallFilter struct {
+	Agent  string        // 只召回指定Agent的记忆
+	Task   string        // 只召回指定会话的记忆
+	Since  time.Time     // 只召回该时间之后的记忆
+	Until  time.Time     // 只召回该时间之前的记忆
+	MaxAge time.Duration // 只召回最近一段时间内的记忆
+}
+
+// match 判断记忆是否满足过滤条件
+func (f RecallFilter) match(item *MemoryItem, now time.Time) bool {
+	if f.Agent != "" && item.Agent != f.Agent {
+		return false
+	}
+	if f.Task != "" && item.Task != f.Task {
+		return false
+	}
+	if !f.Since.IsZero() && item.Time.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && item.Time.After(f.Until) {
+		return false
+	}
+	if f.MaxAge > 0 && now.Sub(item.Time) > f.MaxAge {
+		return false
+	}
+	return true
+}
+
+// ScoredItem 召回结果及其相似度
+type ScoredItem struct {
+	MemoryItem
+	Score float64 `json:"score"`
+}
+
+// VectorIndex 本地向量索引，按余弦相似度检索，可以持久化到JSONL文件
+type VectorIndex struct {
+	items []*MemoryItem
+	path  string // 持久化文件，为空时只保存在内存中
+	mu    sync.RWMutex
+}
+
+// NewVectorIndex 创建内存向量索引
+func NewVectorIndex() *VectorIndex {
+	return &VectorIndex{}
+}
+
+// OpenVectorIndex 打开持久化到文件的向量索引，文件不存在时创建，之后新增的记忆追加写入文件
+func OpenVectorIndex(path string) (*VectorIndex, error) {
+	idx := &VectorIndex{path: path}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return idx, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("open vector index failed: %w", err)
+	}
+	defer f.Close()
+
+	decoder := json.NewDecoder(f)
+	for {
+		item := &MemoryItem{}
+		if err := decoder.Decode(item); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("parse vector index %s failed: %w", path, err)
+		}
+		idx.items = append(idx.items, item)
+	}
+	return idx, nil
+}
+
+// Add 添加记忆
+func (v *VectorIndex) Add(items ...MemoryItem) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.path != "" {
+		if err := v.appendFile(items); err != nil {
+			return err
+		}
+	}
+	for i := range items {
+		item := items[i]
+		v.items = append(v.items, &item)
+	}
+	return nil
+}
+
+// Search 返回与向量最相似的topK条满足过滤条件的记忆，相似度低于minScore的结果被忽略
+func (v *VectorIndex) Search(vector []float32, topK int, minScore float64, filter RecallFilter) []ScoredItem {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	now := time.Now()
+	var results []ScoredItem
+	for _, item := range v.items {
+		if !filter.match(item, now) {
+			continue
+		}
+		score := cosine(vector, item.Vector)
+		if score < minScore {
+			continue
+		}
+		results = append(results, ScoredItem{MemoryItem: *item, Score: score})
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+	if topK > 0 && len(results) > topK {
+		results = results[:topK]
+	}
+	return results
+}
+
+// Len 返回记忆条数
+func (v *VectorIndex) Len() int {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return len(v.items)
+}
+
+// appendFile 将记忆追加写入文件，调用方需持有写锁
+func (v *VectorIndex) appendFile(items []MemoryItem) error {
+	f, err := os.OpenFile(v.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("open vector index failed: %w", err)
+	}
+	defer f.Close()
+	encoder := json.NewEncoder(f)
+	for _, item := range items {
+		if err := encoder.Encode(item); err != nil {
+			return fmt.Errorf("write vector index failed: %w", err)
+		}
+	}
+	return nil
+}
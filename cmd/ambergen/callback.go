@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sync"
+)
+
+// streamCallback 将Agent输出流式写入指定writer
+type streamCallback struct {
+	mu      sync.Mutex
+	w       io.Writer
+	isFirst map[string]bool
+}
+
+func newStreamCallback(w io.Writer) *streamCallback {
+	return &streamCallback{
+		w:       w,
+		isFirst: make(map[string]bool),
+	}
+}
+
+func (c *streamCallback) OnStart(agentName string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.isFirst[agentName] {
+		c.isFirst[agentName] = true
+		fmt.Fprintf(c.w, "\n[%s 开始回答]\n", agentName)
+	}
+}
+
+func (c *streamCallback) OnContent(agentName string, content string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.isFirst[agentName] {
+		fmt.Fprintf(c.w, "[%s]: ", agentName)
+		c.isFirst[agentName] = false
+	}
+	fmt.Fprint(c.w, content)
+}
+
+func (c *streamCallback) OnComplete(agentName string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	fmt.Fprintf(c.w, "\n[%s 回答完成]\n", agentName)
+}
+
+func (c *streamCallback) OnRoundComplete(round int, results map[string]string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	fmt.Fprintf(c.w, "\n=== 第 %d 轮讨论完成 ===\n", round+1)
+}
+
+func (c *streamCallback) OnAllComplete(allResults []map[string]string) {}
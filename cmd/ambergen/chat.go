@@ -0,0 +1,374 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"multi-agent/agent"
+	"multi-agent/config"
+	"multi-agent/oneapi"
+	"multi-agent/workflow"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+)
+
+const chatHelp = `输入内容与当前对象对话，"@名称 内容" 临时向指定Agent提问。
+命令:
+  /agents          列出Agent，* 表示当前对象
+  /use <名称|all>  切换对话对象，all 表示整个工作流（Group或依赖图）
+  /tools           列出当前对象可用的工具
+  /model [名称]    查看或设置当前对象使用的模型
+  /rounds [n]      查看或设置工作流的最大轮数
+  /reset           清空对话记忆
+  /save <文件>     保存会话（记忆、对象、模型和轮数）
+  /load <文件>     加载会话
+  /help            显示帮助
+  /exit            退出
+`
+
+// chatAll 表示与整个工作流对话
+const chatAll = "all"
+
+// chatSession /save 保存的会话
+type chatSession struct {
+	Workflow string                          `json:"workflow"`
+	Target   string                          `json:"target"`
+	Rounds   int                             `json:"rounds"`
+	Models   map[string]string               `json:"models"`
+	Memories map[string][]oneapi.ChatMessage `json:"memories"`
+}
+
+// chatREPL 交互式对话状态
+type chatREPL struct {
+	path    string
+	wf      *workflow.Workflow
+	agents  map[string]agent.Agent
+	target  string
+	rounds  int
+	session *agent.Session // 所有对象共用的会话，切换对象后仍能看到之前的对话
+	out     io.Writer
+}
+
+func chatCommand(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := newFlagSet("chat", "<workflow>", stderr)
+	var common commonFlags
+	common.register(fs)
+	var memory memoryFlags
+	memory.register(fs)
+	target := fs.String("agent", chatAll, "初始对话对象：Agent名称或 all")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return newUsageError("需要一个工作流文件")
+	}
+
+	spec, err := workflow.ParseFile(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	common.apply(spec)
+	if _, err := config.LoadConfig(config.Path); err != nil {
+		return fmt.Errorf("load config failed: %w", err)
+	}
+	if spec.Callback == "" {
+		spec.Callback = "default"
+	}
+	reg := newRegistry(newStreamCallback(stdout))
+	reg.SetApprover(terminalApprover(stdout))
+	reg.SetHumanInput(terminalInput(stdout))
+	wf, err := workflow.Build(spec, reg)
+	if err != nil {
+		return err
+	}
+
+	session, closeStore, err := memory.open()
+	if err != nil {
+		return err
+	}
+	defer closeStore()
+
+	r := &chatREPL{
+		path:    fs.Arg(0),
+		wf:      wf,
+		agents:  make(map[string]agent.Agent),
+		target:  chatAll,
+		rounds:  1,
+		session: session,
+		out:     stdout,
+	}
+	if spec.Group != nil && spec.Group.Rounds > 0 {
+		r.rounds = spec.Group.Rounds
+	}
+	if spec.Graph != nil && spec.Graph.Rounds > 0 {
+		r.rounds = spec.Graph.Rounds
+	}
+	for _, a := range wf.Agents {
+		// 交互模式下始终流式输出
+		if expert, ok := a.(*agent.ExpertAgent); ok {
+			expert.SetStreamOutput(true)
+		}
+		r.agents[a.Name()] = a
+	}
+	if err := r.use(*target); err != nil {
+		return newUsageError("%v", err)
+	}
+
+	fmt.Fprintf(stdout, "ambergen chat: %s（输入 /help 查看命令）\n", r.path)
+	if n := session.Shared().Len(); n > 0 {
+		fmt.Fprintf(stdout, "已加载会话 %s 的 %d 条发言\n", session.ID(), n)
+	}
+	return r.loop(stdin)
+}
+
+// loop 读取输入直到 /exit 或输入结束
+func (r *chatREPL) loop(stdin io.Reader) error {
+	scanner := bufio.NewScanner(stdin)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for {
+		fmt.Fprintf(r.out, "\n%s> ", r.target)
+		if !scanner.Scan() {
+			fmt.Fprintln(r.out)
+			return scanner.Err()
+		}
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "/") {
+			quit, err := r.command(line)
+			if err != nil {
+				fmt.Fprintf(r.out, "错误: %v\n", err)
+			}
+			if quit {
+				return nil
+			}
+			continue
+		}
+
+		if err := r.send(line); err != nil {
+			fmt.Fprintf(r.out, "\n错误: %v\n", err)
+		}
+	}
+}
+
+// send 向当前对象发送一条消息，"@名称 内容" 只对本条消息生效
+func (r *chatREPL) send(line string) error {
+	target := r.target
+	if strings.HasPrefix(line, "@") {
+		name, rest, _ := strings.Cut(line[1:], " ")
+		if name != chatAll && r.agents[name] == nil {
+			return fmt.Errorf("未知的Agent: %s", name)
+		}
+		target, line = name, strings.TrimSpace(rest)
+		if line == "" {
+			return errors.New("消息为空")
+		}
+	}
+
+	// Ctrl+C 只中断当前回答，不退出会话
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	ctx = agent.WithSession(ctx, r.session)
+
+	if target == chatAll {
+		_, err := r.wf.Execute(ctx, line)
+		return err
+	}
+	_, err := r.agents[target].Execute(ctx, line)
+	return err
+}
+
+// command 执行斜杠命令，返回是否退出
+func (r *chatREPL) command(line string) (bool, error) {
+	fields := strings.Fields(line)
+	name, args := fields[0], fields[1:]
+
+	switch name {
+	case "/exit", "/quit":
+		return true, nil
+	case "/help":
+		fmt.Fprint(r.out, chatHelp)
+	case "/agents":
+		for _, a := range r.wf.Agents {
+			mark := " "
+			if a.Name() == r.target {
+				mark = "*"
+			}
+			fmt.Fprintf(r.out, "%s %s（%s）%s\n", mark, a.Name(), strings.Join(a.GetCapabilities(), ", "), modelName(a))
+		}
+		if r.target == chatAll {
+			fmt.Fprintln(r.out, "* all（整个工作流）")
+		}
+	case "/use":
+		if len(args) != 1 {
+			return false, errors.New("用法: /use <名称|all>")
+		}
+		return false, r.use(args[0])
+	case "/tools":
+		count := 0
+		for _, a := range r.experts() {
+			for _, tool := range a.Tools() {
+				fmt.Fprintf(r.out, "%s: %s - %s\n", a.Name(), tool.GetName(), tool.GetDescription())
+				count++
+			}
+		}
+		if count == 0 {
+			fmt.Fprintln(r.out, "没有可用的工具")
+		}
+	case "/model":
+		if len(args) == 0 {
+			for _, a := range r.targets() {
+				fmt.Fprintf(r.out, "%s: %s\n", a.Name(), modelName(a))
+			}
+			return false, nil
+		}
+		for _, a := range r.experts() {
+			a.SetModel(args[0])
+		}
+		fmt.Fprintf(r.out, "已将 %s 的模型设置为 %s\n", r.target, args[0])
+	case "/rounds":
+		if len(args) == 0 {
+			fmt.Fprintf(r.out, "最大轮数: %d\n", r.rounds)
+			return false, nil
+		}
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n < 1 {
+			return false, fmt.Errorf("无效的轮数: %s", args[0])
+		}
+		r.setRounds(n)
+		fmt.Fprintf(r.out, "最大轮数已设置为 %d\n", n)
+	case "/reset":
+		r.session.Reset()
+		fmt.Fprintln(r.out, "对话记忆已清空")
+	case "/save":
+		if len(args) != 1 {
+			return false, errors.New("用法: /save <文件>")
+		}
+		if err := r.save(args[0]); err != nil {
+			return false, err
+		}
+		fmt.Fprintf(r.out, "会话已保存到 %s\n", args[0])
+	case "/load":
+		if len(args) != 1 {
+			return false, errors.New("用法: /load <文件>")
+		}
+		if err := r.load(args[0]); err != nil {
+			return false, err
+		}
+		fmt.Fprintf(r.out, "已加载会话 %s\n", args[0])
+	default:
+		return false, fmt.Errorf("未知命令 %s，输入 /help 查看命令", name)
+	}
+	return false, nil
+}
+
+// use 切换对话对象
+func (r *chatREPL) use(name string) error {
+	if name != chatAll && r.agents[name] == nil {
+		return fmt.Errorf("未知的Agent: %s", name)
+	}
+	r.target = name
+	return nil
+}
+
+// targets 返回当前对象包含的Agent
+func (r *chatREPL) targets() []agent.Agent {
+	if r.target == chatAll {
+		return r.wf.Agents
+	}
+	return []agent.Agent{r.agents[r.target]}
+}
+
+// experts 返回当前对象包含的专家Agent，人类参与者没有工具和模型
+func (r *chatREPL) experts() []*agent.ExpertAgent {
+	var experts []*agent.ExpertAgent
+	for _, a := range r.targets() {
+		if expert, ok := a.(*agent.ExpertAgent); ok {
+			experts = append(experts, expert)
+		}
+	}
+	return experts
+}
+
+func (r *chatREPL) setRounds(n int) {
+	r.rounds = n
+	if r.wf.Group != nil {
+		r.wf.Group.SetMaxRounds(n)
+	} else {
+		r.wf.Graph.SetMaxRounds(n)
+	}
+}
+
+// save 保存会话到文件
+func (r *chatREPL) save(path string) error {
+	session := chatSession{
+		Workflow: r.path,
+		Target:   r.target,
+		Rounds:   r.rounds,
+		Models:   make(map[string]string),
+		Memories: r.session.Snapshot(r.agentList()),
+	}
+	for _, a := range r.wf.Agents {
+		if expert, ok := a.(*agent.ExpertAgent); ok && expert.Model != "" {
+			session.Models[a.Name()] = expert.Model
+		}
+	}
+	data, err := json.MarshalIndent(session, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal session failed: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("write session failed: %w", err)
+	}
+	return nil
+}
+
+// load 从文件加载会话，会话中不存在的Agent将被忽略
+func (r *chatREPL) load(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read session failed: %w", err)
+	}
+	var session chatSession
+	if err := json.Unmarshal(data, &session); err != nil {
+		return fmt.Errorf("parse session %s failed: %w", path, err)
+	}
+
+	r.session.Restore(r.agentList(), session.Memories)
+	for name, model := range session.Models {
+		if expert, ok := r.agents[name].(*agent.ExpertAgent); ok {
+			expert.SetModel(model)
+		}
+	}
+	if session.Rounds > 0 {
+		r.setRounds(session.Rounds)
+	}
+	if r.use(session.Target) != nil {
+		r.target = chatAll
+	}
+	return nil
+}
+
+func (r *chatREPL) agentList() []agent.Agent {
+	return r.wf.Agents
+}
+
+// modelName 返回Agent使用的模型，未设置时为配置中的默认模型
+func modelName(a agent.Agent) string {
+	expert, ok := a.(*agent.ExpertAgent)
+	switch {
+	case !ok:
+		return "人类参与者"
+	case expert.Model == "":
+		return "默认模型"
+	}
+	return expert.Model
+}
@@ -0,0 +1,535 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"multi-agent/agent"
+	"multi-agent/config"
+	"multi-agent/server"
+	"multi-agent/tools"
+	"multi-agent/workflow"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// commonFlags 多个子命令共用的参数
+type commonFlags struct {
+	config string
+	model  string
+	rounds int
+}
+
+func (c *commonFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&c.config, "config", config.Path, "配置文件路径")
+	fs.StringVar(&c.model, "model", "", "覆盖所有Agent使用的模型")
+	fs.IntVar(&c.rounds, "rounds", 0, "覆盖工作流的最大轮数")
+}
+
+// apply 将参数应用到全局配置和工作流定义
+func (c *commonFlags) apply(spec *workflow.Spec) {
+	config.Path = c.config
+	if c.model != "" {
+		for _, a := range spec.Agents {
+			a.Model = c.model
+		}
+	}
+	if c.rounds > 0 {
+		if spec.Group != nil {
+			spec.Group.Rounds = c.rounds
+		}
+		if spec.Graph != nil {
+			spec.Graph.Rounds = c.rounds
+		}
+	}
+}
+
+// newFlagSet 创建子命令参数集，错误由调用方统一输出
+func newFlagSet(name, args string, stderr io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "用法: ambergen %s [flags] %s\n", name, args)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parseFlags 解析参数，将参数错误转换为用法错误
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return err
+		}
+		return newUsageError("%v", err)
+	}
+	return nil
+}
+
+// denyApprover 没有终端时使用，拒绝所有需要批准的工具调用
+type denyApprover struct{}
+
+func (denyApprover) Approve(ctx context.Context, req agent.ApprovalRequest) (agent.ApprovalDecision, error) {
+	return agent.ApprovalDecision{Reason: "没有可用的终端，无法获得人工批准"}, nil
+}
+
+// terminalApprover 返回在终端中询问的审批者。从 /dev/tty 读取回答，
+// 因此输入通过管道传入时也能审批；没有终端时拒绝所有需要批准的调用
+func terminalApprover(prompt io.Writer) agent.Approver {
+	tty, err := os.Open("/dev/tty")
+	if err != nil {
+		return denyApprover{}
+	}
+	return agent.NewTerminalApprover(tty, prompt)
+}
+
+// noTerminalInput 没有终端时使用，人类参与者无法发言
+type noTerminalInput struct{}
+
+func (noTerminalInput) Read(ctx context.Context, prompt agent.HumanPrompt) (string, error) {
+	return "", errors.New("没有可用的终端，无法读取人类参与者的发言")
+}
+
+// terminalInput 返回从终端（/dev/tty）读取人类参与者发言的输入来源
+func terminalInput(prompt io.Writer) agent.HumanInput {
+	tty, err := os.Open("/dev/tty")
+	if err != nil {
+		return noTerminalInput{}
+	}
+	return agent.NewTerminalInput(tty, prompt)
+}
+
+// newRegistry 创建包含内置工具（含黑板工具）的注册表，callback 注册为 default 回调
+func newRegistry(callback agent.OutputCallback) *workflow.Registry {
+	reg := workflow.NewRegistry()
+	reg.RegisterTool(tools.NewCalculator())
+	reg.RegisterTool(tools.NewNewsSearcher())
+	for _, tool := range agent.NewBlackboardTools() {
+		reg.RegisterTool(tool)
+	}
+	if callback != nil {
+		reg.RegisterCallback("default", callback)
+	}
+	return reg
+}
+
+func runCommand(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := newFlagSet("run", "<workflow> [input]", stderr)
+	var common commonFlags
+	common.register(fs)
+	var memory memoryFlags
+	memory.register(fs)
+	jsonOutput := fs.Bool("json", false, "不流式输出，结束后以JSON输出运行记录")
+	save := fs.String("save", "", "结束后将运行记录保存到文件，可用 replay、diff 查看或用 --continue 继续")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() < 1 || fs.NArg() > 2 {
+		fs.Usage()
+		return newUsageError("需要工作流文件和可选的输入")
+	}
+
+	path := fs.Arg(0)
+	input := fs.Arg(1)
+	if fs.NArg() == 1 {
+		data, err := io.ReadAll(stdin)
+		if err != nil {
+			return fmt.Errorf("read input failed: %w", err)
+		}
+		input = string(data)
+	}
+	input = strings.TrimSpace(input)
+	if input == "" {
+		return newUsageError("输入为空")
+	}
+
+	spec, err := workflow.ParseFile(path)
+	if err != nil {
+		return err
+	}
+	common.apply(spec)
+	if _, err := config.LoadConfig(config.Path); err != nil {
+		return fmt.Errorf("load config failed: %w", err)
+	}
+
+	var reg *workflow.Registry
+	if *jsonOutput {
+		// JSON模式下不输出过程，只保留最终结果
+		spec.Callback = ""
+		reg = newRegistry(nil)
+	} else {
+		if spec.Callback == "" {
+			spec.Callback = "default"
+		}
+		reg = newRegistry(newStreamCallback(stdout))
+	}
+
+	reg.SetApprover(terminalApprover(stderr))
+	reg.SetHumanInput(terminalInput(stderr))
+	wf, err := workflow.Build(spec, reg)
+	if err != nil {
+		return err
+	}
+
+	session, closeStore, err := memory.open()
+	if err != nil {
+		return err
+	}
+	defer closeStore()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	ctx = agent.WithSession(ctx, session)
+	recorder := agent.NewRecorder()
+	recorder.SetWorkflow(path)
+	session.SetRecorder(recorder)
+
+	rounds, err := wf.Execute(ctx, input)
+	transcript := recorder.Transcript(session)
+	if *save != "" {
+		// 执行失败时也保存，便于查看失败前的过程
+		if err := transcript.WriteFile(*save); err != nil {
+			return err
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	if *jsonOutput {
+		if err := writeJSON(stdout, transcript); err != nil {
+			return err
+		}
+	} else {
+		fmt.Fprintln(stdout)
+	}
+
+	// 失败策略允许继续执行时，仍以非零退出码提示流水线
+	failed := 0
+	for _, results := range rounds {
+		failed += len(results.Failed())
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d 次Agent执行失败", failed)
+	}
+	return nil
+}
+
+func validateCommand(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := newFlagSet("validate", "<workflow>...", stderr)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return newUsageError("需要至少一个工作流文件")
+	}
+
+	// 只校验定义和引用，不创建Agent，因此不需要配置文件
+	reg := newRegistry(newStreamCallback(io.Discard))
+	failed := 0
+	for _, path := range fs.Args() {
+		spec, err := workflow.ParseFile(path)
+		if err == nil {
+			err = spec.Validate(reg)
+		}
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			failed++
+			continue
+		}
+		fmt.Fprintf(stdout, "%s: ok\n", path)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d 个工作流校验失败", failed)
+	}
+	return nil
+}
+
+func graphCommand(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := newFlagSet("graph", "<workflow>", stderr)
+	format := fs.String("format", "mermaid", "输出格式: mermaid 或 dot")
+	transcript := fs.String("transcript", "", "run --json 保存的运行记录，用于标注执行状态")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return newUsageError("需要一个工作流文件")
+	}
+	if *format != "mermaid" && *format != "dot" {
+		return newUsageError("未知的输出格式: %s", *format)
+	}
+
+	var rounds []agent.RoundResult
+	if *transcript != "" {
+		t, err := agent.ReadTranscript(*transcript)
+		if err != nil {
+			return err
+		}
+		rounds = t.Rounds
+	}
+
+	wf, err := workflow.Load(fs.Arg(0), newRegistry(newStreamCallback(io.Discard)))
+	if err != nil {
+		return err
+	}
+	if *format == "dot" {
+		fmt.Fprint(stdout, wf.ToDOT(rounds))
+	} else {
+		fmt.Fprint(stdout, wf.ToMermaid(rounds))
+	}
+	return nil
+}
+
+func replayCommand(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := newFlagSet("replay", "<transcript>", stderr)
+	round := fs.Int("round", 0, "只回放指定轮次，0 表示全部")
+	messages := fs.Bool("messages", false, "回放每个Agent的完整消息，包括工具调用和结果")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return newUsageError("需要一个运行记录文件")
+	}
+
+	t, err := agent.ReadTranscript(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	if *round < 0 || *round > len(t.Rounds) {
+		return newUsageError("轮次超出范围: %d，共 %d 轮", *round, len(t.Rounds))
+	}
+
+	fmt.Fprintf(stdout, "工作流: %s\n运行ID: %s\n输入: %s\n", t.Workflow, t.RunID, t.Input)
+	for i, results := range t.Rounds {
+		if *round > 0 && i+1 != *round {
+			continue
+		}
+		fmt.Fprintf(stdout, "\n=== 第 %d 轮 ===\n", i+1)
+		if *messages {
+			printEvents(stdout, t, i+1)
+			continue
+		}
+		names := make([]string, 0, len(results))
+		for name := range results {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			r := results[name]
+			switch r.Status {
+			case agent.StatusSkipped:
+				fmt.Fprintf(stdout, "\n[%s 已跳过] %s\n", name, r.Reason)
+			case agent.StatusFailed:
+				fmt.Fprintf(stdout, "\n[%s 执行失败] %s\n", name, r.Error)
+			default:
+				fmt.Fprintf(stdout, "\n[%s]: %s\n", name, r.Content)
+			}
+		}
+	}
+	return nil
+}
+
+func listToolsCommand(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := newFlagSet("list-tools", "", stderr)
+	jsonOutput := fs.Bool("json", false, "以JSON格式输出")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	reg := newRegistry(nil)
+	names := reg.Tools.List()
+	sort.Strings(names)
+
+	if *jsonOutput {
+		type toolInfo struct {
+			Name        string                         `json:"name"`
+			Description string                         `json:"description"`
+			Parameters  map[string]tools.ParameterSpec `json:"parameters"`
+		}
+		list := make([]toolInfo, 0, len(names))
+		for _, name := range names {
+			tool, _ := reg.Tools.Get(name)
+			list = append(list, toolInfo{name, tool.GetDescription(), tool.GetParameters()})
+		}
+		return writeJSON(stdout, list)
+	}
+
+	for _, name := range names {
+		tool, _ := reg.Tools.Get(name)
+		fmt.Fprintf(stdout, "%s\n  %s\n", name, tool.GetDescription())
+		params := tool.GetParameters()
+		paramNames := make([]string, 0, len(params))
+		for p := range params {
+			paramNames = append(paramNames, p)
+		}
+		sort.Strings(paramNames)
+		for _, p := range paramNames {
+			spec := params[p]
+			required := ""
+			if spec.Required {
+				required = "，必需"
+			}
+			fmt.Fprintf(stdout, "    %s (%s%s): %s\n", p, spec.Type, required, spec.Description)
+		}
+	}
+	return nil
+}
+
+// printEvents 按发生顺序输出指定轮次的消息
+func printEvents(w io.Writer, t *agent.Transcript, round int) {
+	for _, e := range t.Events {
+		if e.Round != round {
+			continue
+		}
+		switch {
+		case e.Type == agent.TranscriptError:
+			fmt.Fprintf(w, "\n[%s 执行失败] %s\n", e.Agent, e.Error)
+		case e.Message == nil:
+		case len(e.Message.ToolCalls) > 0:
+			for _, call := range e.Message.ToolCalls {
+				fmt.Fprintf(w, "\n[%s 调用工具] %s(%s)\n", e.Agent, call.Function.Name, call.Function.Arguments)
+			}
+		case e.Message.Role == "tool":
+			fmt.Fprintf(w, "\n[%s 工具结果] %s\n", e.Agent, e.Message.Content)
+		default:
+			fmt.Fprintf(w, "\n[%s %s] %s\n", e.Agent, e.Message.Role, e.Message.Content)
+		}
+	}
+}
+
+func diffCommand(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := newFlagSet("diff", "<transcript> <transcript>", stderr)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return newUsageError("需要两个运行记录文件")
+	}
+	a, err := agent.ReadTranscript(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	b, err := agent.ReadTranscript(fs.Arg(1))
+	if err != nil {
+		return err
+	}
+
+	diffs := agent.DiffTranscripts(a, b)
+	for _, d := range diffs {
+		fmt.Fprintf(stdout, "第 %d 轮 %s %s:\n- %s\n+ %s\n\n", d.Round, d.Agent, d.Field,
+			strings.TrimRight(d.A, "\n"), strings.TrimRight(d.B, "\n"))
+	}
+	if len(diffs) > 0 {
+		// 与 diff 命令一致，有差异时返回非零退出码
+		return fmt.Errorf("%d 处差异", len(diffs))
+	}
+	fmt.Fprintln(stdout, "两次运行的结果和工具调用相同")
+	return nil
+}
+
+func writeJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(v)
+}
+
+func serveCommand(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := newFlagSet("serve", "<workflow>...", stderr)
+	var common commonFlags
+	common.register(fs)
+	addr := fs.String("addr", ":8080", "监听地址")
+	workers := fs.Int("workers", 4, "异步运行的并发数")
+	queueSize := fs.Int("queue", 100, "异步运行的最大排队数")
+	maxRuns := fs.Int("max-runs", server.DefaultMaxRuns, "最多保留的已结束运行数，0表示不限制")
+	allowDefinitions := fs.Bool("allow-definitions", false, "接受 /v1/runs 请求中内联的工作流定义")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return newUsageError("需要至少一个工作流文件")
+	}
+
+	// 内置工具在创建时读取配置，需要先应用 --config
+	config.Path = common.config
+	reg := newRegistry(nil)
+	store := server.NewMemoryRunStore()
+	store.SetMaxRuns(*maxRuns)
+	runs := server.NewRunManager(store, reg, *workers, *queueSize)
+	runs.AllowDefinitions(*allowDefinitions)
+	defer runs.Close()
+	// 人类参与者通过 /v1/runs/{id}/input 发言，工具调用通过 /v1/runs/{id}/approvals 审批
+	reg.SetHumanInput(runs.HumanInput())
+	reg.SetApprover(runs.Approver())
+	srv := server.NewServer()
+	srv.SetRunManager(runs)
+	for _, path := range fs.Args() {
+		spec, err := workflow.ParseFile(path)
+		if err != nil {
+			return err
+		}
+		common.apply(spec)
+		// 服务按请求传递输出回调，不使用工作流中的回调
+		spec.Callback = ""
+		wf, err := workflow.Build(spec, reg)
+		if err != nil {
+			return err
+		}
+		name := workflowName(spec, path)
+		if err := registerWorkflow(srv, name, wf); err != nil {
+			return err
+		}
+		if err := runs.RegisterWorkflow(name, spec); err != nil {
+			return err
+		}
+	}
+	if _, err := config.LoadConfig(config.Path); err != nil {
+		return fmt.Errorf("load config failed: %w", err)
+	}
+
+	fmt.Fprintf(stdout, "listening on %s (runs: /v1/runs)\n", *addr)
+	for _, name := range srv.Models() {
+		fmt.Fprintf(stdout, "  model: %s\n", name)
+	}
+	return http.ListenAndServe(*addr, srv)
+}
+
+// registerWorkflow 将工作流注册为模型 name，其中每个Agent注册为 name/Agent名称
+func registerWorkflow(srv *server.Server, name string, wf *workflow.Workflow) error {
+	var err error
+	if wf.Group != nil {
+		err = srv.RegisterGroup(name, wf.Group)
+	} else {
+		err = srv.RegisterGraph(name, wf.Graph)
+	}
+	if err != nil {
+		return err
+	}
+	for _, a := range wf.Agents {
+		if err := srv.RegisterAgent(name+"/"+a.Name(), a); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// workflowName 返回工作流名称，未设置时使用文件名
+func workflowName(spec *workflow.Spec, path string) string {
+	if spec.Name != "" {
+		return spec.Name
+	}
+	base := filepath.Base(path)
+	return strings.TrimSuffix(base, filepath.Ext(base))
+}
@@ -0,0 +1,98 @@
+// ambergen 命令行工具：运行、校验和查看声明式工作流
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+const usage = `用法: ambergen <命令> [参数]
+
+命令:
+  run [flags] <workflow> [input]   运行工作流，input 为空时从标准输入读取
+  chat [flags] <workflow>          与Agent或整个工作流交互式对话
+  validate <workflow>...           校验工作流文件
+  graph [flags] <workflow>         输出工作流拓扑（mermaid 或 dot）
+  replay [flags] <transcript>      回放 run --json 或 run --save 保存的运行记录
+  diff <transcript> <transcript>   比较两次运行的结果和工具调用
+  list-tools [flags]               列出可用的工具
+  serve [flags] <workflow>...      以OpenAI兼容接口提供工作流和Agent
+  memory [flags] list|show|delete  查看或删除持久化的记忆
+
+使用 "ambergen <命令> -h" 查看命令的参数。
+`
+
+// command 子命令
+type command struct {
+	name string
+	run  func(args []string, stdin io.Reader, stdout, stderr io.Writer) error
+}
+
+var commands = []command{
+	{"run", runCommand},
+	{"chat", chatCommand},
+	{"validate", validateCommand},
+	{"graph", graphCommand},
+	{"replay", replayCommand},
+	{"diff", diffCommand},
+	{"list-tools", listToolsCommand},
+	{"serve", serveCommand},
+	{"memory", memoryCommand},
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run 执行命令并返回退出码：0 成功，1 执行失败，2 用法错误
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "--help" || args[0] == "help" {
+		fmt.Fprint(stderr, usage)
+		if len(args) == 0 {
+			return 2
+		}
+		return 0
+	}
+
+	for _, cmd := range commands {
+		if cmd.name != args[0] {
+			continue
+		}
+		err := cmd.run(args[1:], stdin, stdout, stderr)
+		switch {
+		case err == nil:
+			return 0
+		case err == flag.ErrHelp:
+			return 0
+		case isUsageError(err):
+			fmt.Fprintf(stderr, "ambergen %s: %v\n", cmd.name, err)
+			return 2
+		default:
+			fmt.Fprintf(stderr, "ambergen %s: %v\n", cmd.name, err)
+			return 1
+		}
+	}
+
+	fmt.Fprintf(stderr, "未知命令: %s\n\n%s", args[0], usage)
+	return 2
+}
+
+// usageError 参数错误
+type usageError struct {
+	msg string
+}
+
+func (e *usageError) Error() string {
+	return e.msg
+}
+
+func newUsageError(format string, args ...interface{}) error {
+	return &usageError{msg: fmt.Sprintf(format, args...)}
+}
+
+func isUsageError(err error) bool {
+	_, ok := err.(*usageError)
+	return ok
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"multi-agent/agent"
+	"strings"
+)
+
+// memoryFlags 持久化记忆的参数
+type memoryFlags struct {
+	store      string
+	session    string
+	transcript string
+}
+
+func (m *memoryFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&m.store, "memory", "", "记忆存储：目录（每个会话一个JSONL文件）或 .db 文件（SQLite），为空时不持久化")
+	fs.StringVar(&m.session, "session", "", "会话ID，配合 --memory 使用相同的ID可以继续之前的对话")
+	fs.StringVar(&m.transcript, "continue", "", "从运行记录恢复记忆和黑板，在之前运行的基础上继续")
+}
+
+// open 创建会话，设置了存储时从存储加载之前的记忆，设置了运行记录时再用记录中的记忆覆盖。
+// 返回的函数用于关闭存储
+func (m *memoryFlags) open() (*agent.Session, func(), error) {
+	var t *agent.Transcript
+	if m.transcript != "" {
+		var err error
+		if t, err = agent.ReadTranscript(m.transcript); err != nil {
+			return nil, nil, err
+		}
+	}
+	if m.store == "" {
+		session := agent.NewSession(m.session)
+		if t != nil {
+			t.Restore(session)
+		}
+		return session, func() {}, nil
+	}
+	store, closeStore, err := openMemoryStore(m.store)
+	if err != nil {
+		return nil, nil, err
+	}
+	session, err := agent.NewPersistentSession(m.session, store)
+	if err != nil {
+		closeStore()
+		return nil, nil, err
+	}
+	if t != nil {
+		t.Restore(session)
+	}
+	return session, closeStore, nil
+}
+
+// openMemoryStore 根据路径打开记忆存储：.db、.sqlite 文件使用SQLite，其余作为JSONL目录
+func openMemoryStore(path string) (agent.MemoryStore, func(), error) {
+	for _, ext := range []string{".db", ".sqlite", ".sqlite3"} {
+		if strings.HasSuffix(path, ext) {
+			store, err := agent.NewSQLiteMemoryStore(path)
+			if err != nil {
+				return nil, nil, err
+			}
+			return store, func() { store.Close() }, nil
+		}
+	}
+	store, err := agent.NewFileMemoryStore(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return store, func() {}, nil
+}
+
+func memoryCommand(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := newFlagSet("memory", "list | show <session> | delete <session>", stderr)
+	path := fs.String("memory", "", "记忆存储：目录或 .db 文件")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *path == "" {
+		fs.Usage()
+		return newUsageError("需要 --memory 指定记忆存储")
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return newUsageError("需要子命令 list、show 或 delete")
+	}
+	action := fs.Arg(0)
+	if action == "list" && fs.NArg() != 1 || action != "list" && fs.NArg() != 2 {
+		fs.Usage()
+		return newUsageError("参数数量错误")
+	}
+
+	store, closeStore, err := openMemoryStore(*path)
+	if err != nil {
+		return err
+	}
+	defer closeStore()
+
+	switch action {
+	case "list":
+		ids, err := store.Sessions()
+		if err != nil {
+			return err
+		}
+		for _, id := range ids {
+			fmt.Fprintln(stdout, id)
+		}
+	case "show":
+		names, err := store.Memories(fs.Arg(1))
+		if err != nil {
+			return err
+		}
+		if len(names) == 0 {
+			return fmt.Errorf("会话 %s 没有记忆", fs.Arg(1))
+		}
+		for _, name := range names {
+			history, err := store.Load(fs.Arg(1), name)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(stdout, "== %s（%d 条）==\n", name, len(history))
+			for _, msg := range history {
+				fmt.Fprintf(stdout, "[%s] %s\n", msg.Role, msg.Content)
+				for _, call := range msg.ToolCalls {
+					fmt.Fprintf(stdout, "  调用工具 %s %s\n", call.Function.Name, call.Function.Arguments)
+				}
+			}
+		}
+	case "delete":
+		return store.Delete(fs.Arg(1))
+	default:
+		return newUsageError("未知的子命令: %s", action)
+	}
+	return nil
+}
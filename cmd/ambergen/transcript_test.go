@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"multi-agent/agent"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "更新 testdata 中的 golden 文件")
+
+// checkGolden 比较输出与 testdata/<name>.golden，-update 时重新生成
+func checkGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name+".golden")
+	if *update {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s output differs from %s:\n%s", name, path, got)
+	}
+}
+
+func TestDiffCommand(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	err := diffCommand([]string{"testdata/run_a.json", "testdata/run_b.json"}, nil, &stdout, &stderr)
+	if err == nil || err.Error() != "5 处差异" {
+		t.Errorf("got %v, want 5 处差异", err)
+	}
+	checkGolden(t, "diff", stdout.Bytes())
+
+	stdout.Reset()
+	if err := diffCommand([]string{"testdata/run_a.json", "testdata/run_a.json"}, nil, &stdout, &stderr); err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, "diff_same", stdout.Bytes())
+}
+
+func TestReplayCommand(t *testing.T) {
+	for name, args := range map[string][]string{
+		"replay":          {"testdata/run_b.json"},
+		"replay_messages": {"--messages", "testdata/run_a.json"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if err := replayCommand(args, nil, &stdout, &stderr); err != nil {
+				t.Fatal(err)
+			}
+			checkGolden(t, name, stdout.Bytes())
+		})
+	}
+}
+
+func TestTranscriptUnsupportedVersion(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	err := replayCommand([]string{"testdata/run_future.json"}, nil, &stdout, &stderr)
+	if !errors.Is(err, agent.ErrUnsupportedTranscript) {
+		t.Errorf("replay: got %v, want ErrUnsupportedTranscript", err)
+	}
+	err = diffCommand([]string{"testdata/run_a.json", "testdata/run_future.json"}, nil, &stdout, &stderr)
+	if !errors.Is(err, agent.ErrUnsupportedTranscript) {
+		t.Errorf("diff: got %v, want ErrUnsupportedTranscript", err)
+	}
+	if stdout.Len() != 0 {
+		t.Errorf("unsupported transcript produced output: %s", stdout.String())
+	}
+}
@@ -12,8 +12,13 @@ type Config struct {
 	Model           string `json:"model"`              // 模型
 	WebSearchApiKey string `json:"web_search_api_key"` // web_search_api_key
 	WebSearchUrl    string `json:"web_search_url"`     // web_search_url
+	// ContextLimits 模型上下文长度（Token），按模型名前缀匹配，覆盖内置值
+	ContextLimits map[string]int `json:"context_limits,omitempty"`
 }
 
+// Path 默认配置文件路径，可在程序启动时修改
+var Path = "config.json"
+
 // LoadConfig 加载配置文件
 func LoadConfig(path string) (*Config, error) {
 	data, err := os.ReadFile(path)
@@ -0,0 +1,32 @@
+package examples
+
+import (
+	"context"
+	"log"
+	"multi-agent/tools"
+	"multi-agent/workflow"
+)
+
+// NewDefaultRegistry 创建包含内置工具和默认回调的工作流注册表
+func NewDefaultRegistry() *workflow.Registry {
+	reg := workflow.NewRegistry()
+	reg.RegisterTool(tools.NewCalculator())
+	reg.RegisterTool(tools.NewNewsSearcher())
+	reg.RegisterCallback("default", NewDefaultOutputCallback())
+	return reg
+}
+
+func WorkflowExample() {
+	// 从声明式文件构建Agent和依赖图
+	wf, err := workflow.Load("examples/workflows/review.yaml", NewDefaultRegistry())
+	if err != nil {
+		// 错误中包含文件名和行号，例如 review.yaml:12:5: 未定义的Agent: editor
+		log.Fatal("加载工作流失败:\n", err)
+	}
+
+	ctx := context.Background()
+	_, err = wf.Execute(ctx, "请写一篇介绍OAuth2认证流程的技术文章")
+	if err != nil {
+		log.Fatal("执行失败:", err)
+	}
+}
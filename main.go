@@ -15,6 +15,8 @@ func main() {
 	// examples.ToolUsageExample()
 	// 选择最合适智能体示例
 	// examples.SelectorTestExample()
+	// 声明式工作流示例
+	// examples.WorkflowExample()
 	// 联网搜素示例
 	examples.WebSearchExample()
 }
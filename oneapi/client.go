@@ -7,7 +7,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
 	"multi-agent/config"
 	"net/http"
 	"strings"
@@ -18,23 +17,43 @@ type Client struct {
 	BaseURL string
 	Model   string
 	client  *http.Client
+	err     error          // 加载配置的错误，在请求时返回
+	limits  map[string]int // 配置文件中的模型上下文长度
 }
 
+// NewClient 使用配置文件创建客户端。配置加载失败时仍返回客户端（便于仅查看拓扑等场景），
+// 错误在发起请求时返回
 func NewClient() *Client {
-	cfg, err := config.LoadConfig("config.json")
+	cfg, err := config.LoadConfig(config.Path)
 	if err != nil {
-		log.Fatal("加载配置失败:", err)
+		return &Client{err: err, client: &http.Client{}}
 	}
 	return &Client{
 		APIKey:  cfg.APIKey,
 		BaseURL: cfg.BaseURL,
 		Model:   cfg.Model,
 		client:  &http.Client{},
+		limits:  cfg.ContextLimits,
 	}
 }
 
+// ContextLimit 返回模型的上下文长度，model为空时使用客户端默认模型。
+// 优先使用配置文件中的 context_limits，其次使用内置值
+func (c *Client) ContextLimit(model string) int {
+	if model == "" {
+		model = c.Model
+	}
+	if limit, ok := lookupContextLimit(c.limits, model); ok {
+		return limit
+	}
+	return ContextLimit(model)
+}
+
 // ChatCompletion 支持流式和非流式输出
 func (c *Client) ChatCompletion(ctx context.Context, req ChatCompletionRequest, callback func(string)) (*ChatCompletionResponse, error) {
+	if c.err != nil {
+		return nil, fmt.Errorf("load config failed: %w", c.err)
+	}
 	if req.Stream {
 		return c.streamChatCompletion(ctx, req, callback)
 	}
@@ -88,12 +107,16 @@ func (c *Client) normalChatCompletion(ctx context.Context, req ChatCompletionReq
 			} `json:"error"`
 		}
 		if err := json.Unmarshal(body, &errorResp); err != nil {
-			return nil, fmt.Errorf("HTTP error %d: %s", resp.StatusCode, string(body))
+			return nil, httpError(resp.StatusCode, string(body))
 		}
-		return nil, fmt.Errorf("API error: %s (type: %s, code: %s)",
+		err := fmt.Errorf("API error: %s (type: %s, code: %s)",
 			errorResp.Error.Message,
 			errorResp.Error.Type,
 			errorResp.Error.Code)
+		if isContextLengthError(errorResp.Error.Code + " " + errorResp.Error.Message) {
+			err = fmt.Errorf("%w: %v", ErrContextLengthExceeded, err)
+		}
+		return nil, err
 	}
 
 	// 解析响应
@@ -136,7 +159,7 @@ func (c *Client) streamChatCompletion(ctx context.Context, req ChatCompletionReq
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("HTTP error %d: %s", resp.StatusCode, string(body))
+		return nil, httpError(resp.StatusCode, string(body))
 	}
 
 	reader := bufio.NewReader(resp.Body)
@@ -176,6 +199,10 @@ func (c *Client) streamChatCompletion(ctx context.Context, req ChatCompletionReq
 			continue
 		}
 
+		if streamResp.Usage != nil {
+			response.Usage = *streamResp.Usage
+		}
+
 		if len(streamResp.Choices) > 0 {
 			choice := streamResp.Choices[0]
 
@@ -252,3 +279,34 @@ func (c *Client) streamChatCompletion(ctx context.Context, req ChatCompletionReq
 	}
 	return response, nil
 }
+
+// httpError 构造HTTP错误，上下文超长时包装 ErrContextLengthExceeded
+func httpError(status int, body string) error {
+	if isContextLengthError(body) {
+		return fmt.Errorf("%w: HTTP error %d: %s", ErrContextLengthExceeded, status, body)
+	}
+	return fmt.Errorf("HTTP error %d: %s", status, body)
+}
+
+// contextLengthMarkers 各服务商上下文超长错误中常见的关键字
+var contextLengthMarkers = []string{
+	"context_length_exceeded",
+	"maximum context length",
+	"context length",
+	"context window",
+	"too many tokens",
+	"prompt is too long",
+	"input is too long",
+	"range of input length",
+}
+
+// isContextLengthError 判断错误信息是否表示上下文超长
+func isContextLengthError(msg string) bool {
+	msg = strings.ToLower(msg)
+	for _, marker := range contextLengthMarkers {
+		if strings.Contains(msg, marker) {
+			return true
+		}
+	}
+	return false
+}
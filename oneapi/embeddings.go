@@ -0,0 +1,80 @@
+package oneapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// EmbeddingRequest 向量化请求
+type EmbeddingRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+// Embedding 单条文本的向量
+type Embedding struct {
+	Index     int       `json:"index"`
+	Embedding []float32 `json:"embedding"`
+}
+
+// EmbeddingResponse 向量化响应
+type EmbeddingResponse struct {
+	Data  []Embedding `json:"data"`
+	Model string      `json:"model"`
+	Usage Usage       `json:"usage"`
+}
+
+// Embeddings 调用 /v1/embeddings 将文本转换为向量，返回的向量与输入一一对应
+func (c *Client) Embeddings(ctx context.Context, model string, input []string) ([][]float32, error) {
+	if c.err != nil {
+		return nil, fmt.Errorf("load config failed: %w", c.err)
+	}
+	url := fmt.Sprintf("%s/v1/embeddings", c.BaseURL)
+	jsonData, err := json.Marshal(EmbeddingRequest{Model: model, Input: input})
+	if err != nil {
+		return nil, fmt.Errorf("marshal request failed: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("create request failed: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.APIKey))
+
+	resp, err := c.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read response failed: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, httpError(resp.StatusCode, string(body))
+	}
+
+	var response EmbeddingResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("decode response failed: %w", err)
+	}
+	vectors := make([][]float32, len(input))
+	for _, d := range response.Data {
+		if d.Index < 0 || d.Index >= len(vectors) {
+			return nil, fmt.Errorf("invalid embedding index %d", d.Index)
+		}
+		vectors[d.Index] = d.Embedding
+	}
+	for i, v := range vectors {
+		if v == nil {
+			return nil, fmt.Errorf("missing embedding for input %d", i)
+		}
+	}
+	return vectors, nil
+}
@@ -0,0 +1,9 @@
+package oneapi
+
+import "errors"
+
+// 定义错误常量
+var (
+	// ErrContextLengthExceeded 请求的消息超出模型的上下文长度
+	ErrContextLengthExceeded = errors.New("context length exceeded")
+)
@@ -0,0 +1,147 @@
+package oneapi
+
+import (
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Tokenizer 计算文本的Token数量，可替换为与模型一致的分词器以获得精确结果
+type Tokenizer interface {
+	CountTokens(text string) int
+}
+
+// EstimateTokenizer 基于字符类别的Token估算器，无需下载词表
+//
+// 汉字、假名、谚文按每字1个Token计算，连续的字母数字按每4个字符1个Token计算，
+// 其余标点符号各计1个Token，空白不计。结果与BPE分词器接近，通常略为偏高。
+type EstimateTokenizer struct{}
+
+// CountTokens 估算文本的Token数量
+func (EstimateTokenizer) CountTokens(text string) int {
+	tokens := 0
+	word := 0
+	flush := func() {
+		tokens += (word + 3) / 4
+		word = 0
+	}
+	for _, r := range text {
+		switch {
+		case unicode.IsSpace(r):
+			flush()
+		case unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul):
+			flush()
+			tokens++
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			word++
+		default:
+			flush()
+			tokens++
+		}
+	}
+	flush()
+	return tokens
+}
+
+const (
+	messageOverhead = 4 // 每条消息的角色、分隔符等额外Token
+	replyOverhead   = 3 // 回复的起始Token
+)
+
+var (
+	tokenizerMu sync.RWMutex
+	tokenizer   Tokenizer = EstimateTokenizer{}
+)
+
+// SetTokenizer 设置全局使用的分词器，传入nil恢复默认估算器
+func SetTokenizer(t Tokenizer) {
+	tokenizerMu.Lock()
+	defer tokenizerMu.Unlock()
+	if t == nil {
+		t = EstimateTokenizer{}
+	}
+	tokenizer = t
+}
+
+// CountTokens 使用全局分词器计算文本的Token数量
+func CountTokens(text string) int {
+	tokenizerMu.RLock()
+	t := tokenizer
+	tokenizerMu.RUnlock()
+	return t.CountTokens(text)
+}
+
+// CountMessageTokens 计算单条消息占用的Token数量，包括工具调用
+func CountMessageTokens(msg ChatMessage) int {
+	tokens := messageOverhead + CountTokens(msg.Content)
+	for _, call := range msg.ToolCalls {
+		tokens += CountTokens(call.Function.Name) + CountTokens(call.Function.Arguments)
+	}
+	return tokens
+}
+
+// CountMessagesTokens 计算一组消息作为请求发送时占用的Token数量
+func CountMessagesTokens(messages []ChatMessage) int {
+	tokens := replyOverhead
+	for _, msg := range messages {
+		tokens += CountMessageTokens(msg)
+	}
+	return tokens
+}
+
+// DefaultContextLimit 未知模型使用的上下文长度
+const DefaultContextLimit = 8192
+
+var (
+	contextLimitsMu sync.RWMutex
+	// contextLimits 常见模型的上下文长度，按最长前缀匹配
+	contextLimits = map[string]int{
+		"gpt-3.5-turbo":     16385,
+		"gpt-4":             8192,
+		"gpt-4-32k":         32768,
+		"gpt-4-turbo":       128000,
+		"gpt-4o":            128000,
+		"gpt-4.1":           1047576,
+		"o1":                200000,
+		"o3":                200000,
+		"claude":            200000,
+		"deepseek-chat":     65536,
+		"deepseek-reasoner": 65536,
+		"qwen-turbo":        131072,
+		"qwen-plus":         131072,
+		"qwen-max":          32768,
+		"qwen-long":         1000000,
+		"glm-4":             128000,
+		"moonshot-v1-8k":    8192,
+		"moonshot-v1-32k":   32768,
+		"moonshot-v1-128k":  128000,
+	}
+)
+
+// SetContextLimit 设置模型的上下文长度，模型名按前缀匹配
+func SetContextLimit(model string, limit int) {
+	contextLimitsMu.Lock()
+	defer contextLimitsMu.Unlock()
+	contextLimits[model] = limit
+}
+
+// ContextLimit 返回模型的上下文长度，取最长匹配前缀，未知模型返回 DefaultContextLimit
+func ContextLimit(model string) int {
+	contextLimitsMu.RLock()
+	defer contextLimitsMu.RUnlock()
+	if limit, ok := lookupContextLimit(contextLimits, model); ok {
+		return limit
+	}
+	return DefaultContextLimit
+}
+
+// lookupContextLimit 返回最长匹配前缀的上下文长度，没有匹配时ok为false
+func lookupContextLimit(limits map[string]int, model string) (limit int, ok bool) {
+	best := -1
+	for prefix, l := range limits {
+		if strings.HasPrefix(model, prefix) && len(prefix) > best {
+			best, limit = len(prefix), l
+		}
+	}
+	return limit, best >= 0
+}
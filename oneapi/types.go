@@ -65,6 +65,21 @@ type ChatCompletionResponse struct {
 	Object  string   `json:"object"`
 	Created int64    `json:"created"`
 	Model   string   `json:"model"`
+	Usage   Usage    `json:"usage"`
+}
+
+// Usage Token用量
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// Add 累加Token用量
+func (u *Usage) Add(other Usage) {
+	u.PromptTokens += other.PromptTokens
+	u.CompletionTokens += other.CompletionTokens
+	u.TotalTokens += other.TotalTokens
 }
 
 type StreamResponse struct {
@@ -89,6 +104,7 @@ type StreamResponse struct {
 		} `json:"delta,omitempty"`
 		FinishReason string `json:"finish_reason,omitempty"`
 	} `json:"choices,omitempty"`
+	Usage *Usage `json:"usage,omitempty"` // 部分服务在最后一个分片中返回用量
 }
 
 type toolCallState struct {
@@ -0,0 +1,17 @@
+package server
+
+import "errors"
+
+// 定义错误常量
+var (
+	ErrModelExists      = errors.New("model already registered")
+	ErrRunNotFound      = errors.New("run not found")
+	ErrWorkflowNotFound = errors.New("workflow not found")
+	ErrQueueFull        = errors.New("run queue is full")
+	ErrRunFinished      = errors.New("run already finished")
+	ErrManagerClosed    = errors.New("run manager closed")
+	ErrInvalidRequest   = errors.New("invalid request")
+	ErrInputNotFound    = errors.New("input request not found")
+	ErrApprovalNotFound = errors.New("approval request not found")
+	ErrDefinitionDenied = errors.New("inline workflow definitions are disabled")
+)
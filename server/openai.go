@@ -0,0 +1,168 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"multi-agent/agent"
+	"multi-agent/oneapi"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// chatCompletionRequest 请求中服务关心的字段，其余字段（temperature等）忽略
+type chatCompletionRequest struct {
+	Model    string               `json:"model"`
+	Messages []oneapi.ChatMessage `json:"messages"`
+	Stream   bool                 `json:"stream"`
+}
+
+// chatCompletionChunk 流式输出的分片
+type chatCompletionChunk struct {
+	ID      string        `json:"id"`
+	Object  string        `json:"object"`
+	Created int64         `json:"created"`
+	Model   string        `json:"model"`
+	Choices []chunkChoice `json:"choices"`
+	Usage   *oneapi.Usage `json:"usage,omitempty"`
+}
+
+type chunkChoice struct {
+	Index        int        `json:"index"`
+	Delta        chunkDelta `json:"delta"`
+	FinishReason *string    `json:"finish_reason"`
+}
+
+type chunkDelta struct {
+	Role    string `json:"role,omitempty"`
+	Content string `json:"content,omitempty"`
+	Agent   string `json:"agent,omitempty"` // 产生该内容的Agent
+}
+
+func (s *Server) handleChatCompletions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "invalid_request_error", "method not allowed")
+		return
+	}
+
+	var req chatCompletionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", fmt.Sprintf("invalid request body: %v", err))
+		return
+	}
+	m, ok := s.getModel(req.Model)
+	if !ok {
+		writeError(w, http.StatusNotFound, "model_not_found", fmt.Sprintf("model %s does not exist", req.Model))
+		return
+	}
+	if len(req.Messages) == 0 {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", "messages is empty")
+		return
+	}
+	last := req.Messages[len(req.Messages)-1]
+	if last.Role != "user" || last.Content == "" {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", "last message must be a non-empty user message")
+		return
+	}
+
+	id := fmt.Sprintf("chatcmpl-%d", time.Now().UnixNano())
+	created := time.Now().Unix()
+
+	// 每个请求使用独立的会话，以请求中的历史消息作为对话记忆
+	agents := m.agents()
+	history := req.Messages[:len(req.Messages)-1]
+	memories := make(map[string][]oneapi.ChatMessage, len(agents))
+	for _, a := range agents {
+		memories[a.Name()] = history
+	}
+	session := agent.NewSession(id)
+	session.Restore(agents, memories)
+	ctx := agent.WithSession(r.Context(), session)
+
+	if !req.Stream {
+		// 非流式请求不输出中间内容
+		output, usage, err := m.execute(agent.WithCallback(ctx, nil), last.Content)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, oneapi.ChatCompletionResponse{
+			ID:      id,
+			Object:  "chat.completion",
+			Created: created,
+			Model:   m.name,
+			Choices: []oneapi.Choice{{
+				Message:      oneapi.ChatMessage{Role: "assistant", Content: output},
+				FinishReason: "stop",
+			}},
+			Usage: usage,
+		})
+		return
+	}
+
+	stream, err := newSSEStream(w)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+		return
+	}
+	cb := &streamCallback{
+		stream: stream,
+		tagged: m.kind != KindAgent,
+		chunk: chatCompletionChunk{
+			ID:      id,
+			Object:  "chat.completion.chunk",
+			Created: created,
+			Model:   m.name,
+		},
+	}
+	cb.send(chunkDelta{Role: "assistant"}, nil, nil)
+
+	_, usage, err := m.execute(agent.WithCallback(ctx, cb), last.Content)
+
+	if err != nil {
+		stream.event(newErrorResponse("server_error", err.Error()))
+		stream.done()
+		return
+	}
+	stop := "stop"
+	cb.send(chunkDelta{}, &stop, &usage)
+	stream.done()
+}
+
+// streamCallback 将Agent输出转换为 chat.completion.chunk 分片
+type streamCallback struct {
+	mu      sync.Mutex
+	stream  *sseStream
+	chunk   chatCompletionChunk
+	tagged  bool   // 多个Agent时在切换发言者时插入 [名称] 标记
+	speaker string // 最近一次输出内容的Agent
+}
+
+func (c *streamCallback) send(delta chunkDelta, finishReason *string, usage *oneapi.Usage) {
+	chunk := c.chunk
+	chunk.Choices = []chunkChoice{{Delta: delta, FinishReason: finishReason}}
+	chunk.Usage = usage
+	c.stream.event(chunk)
+}
+
+func (c *streamCallback) OnStart(agentName string) {}
+
+func (c *streamCallback) OnContent(agentName string, content string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.tagged && c.speaker != agentName {
+		prefix := fmt.Sprintf("[%s]: ", agentName)
+		if c.speaker != "" {
+			prefix = "\n\n" + prefix
+		}
+		content = prefix + content
+	}
+	c.speaker = agentName
+	c.send(chunkDelta{Content: content, Agent: agentName}, nil, nil)
+}
+
+func (c *streamCallback) OnComplete(agentName string) {}
+
+func (c *streamCallback) OnRoundComplete(round int, results map[string]string) {}
+
+func (c *streamCallback) OnAllComplete(allResults []map[string]string) {}
@@ -0,0 +1,309 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"multi-agent/agent"
+	"multi-agent/config"
+	"multi-agent/oneapi"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeLLM 模拟 OpenAI 兼容接口，回复最后一条消息，每次请求用量为 tokensPerCall。
+// 请求带有工具且尚未调用过工具时，先以固定参数调用第一个工具
+type fakeLLM struct {
+	delay    time.Duration
+	inFlight int32
+	maxSeen  int32
+}
+
+const tokensPerCall = 10
+
+func (f *fakeLLM) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	n := atomic.AddInt32(&f.inFlight, 1)
+	defer atomic.AddInt32(&f.inFlight, -1)
+	for {
+		seen := atomic.LoadInt32(&f.maxSeen)
+		if n <= seen || atomic.CompareAndSwapInt32(&f.maxSeen, seen, n) {
+			break
+		}
+	}
+	time.Sleep(f.delay)
+
+	var req oneapi.ChatCompletionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	message := oneapi.ChatMessage{Role: "assistant", Content: "reply: " + req.Messages[len(req.Messages)-1].Content}
+	if len(req.Tools) > 0 && !hasToolResult(req.Messages) {
+		call := oneapi.ToolCall{ID: "call_1", Type: "function"}
+		call.Function.Name = req.Tools[0].Function.Name
+		call.Function.Arguments = `{"a": 1, "b": 2, "operation": "add"}`
+		message = oneapi.ChatMessage{Role: "assistant", ToolCalls: []oneapi.ToolCall{call}}
+	}
+	json.NewEncoder(w).Encode(oneapi.ChatCompletionResponse{
+		ID:      "chatcmpl-test",
+		Object:  "chat.completion",
+		Model:   req.Model,
+		Choices: []oneapi.Choice{{Message: message}},
+		Usage:   oneapi.Usage{TotalTokens: tokensPerCall},
+	})
+}
+
+func hasToolResult(messages []oneapi.ChatMessage) bool {
+	for _, msg := range messages {
+		if msg.Role == "tool" {
+			return true
+		}
+	}
+	return false
+}
+
+// useFakeLLM 启动模拟接口并让之后创建的Agent使用它
+func useFakeLLM(t *testing.T, llm *fakeLLM) {
+	t.Helper()
+	srv := httptest.NewServer(llm)
+	t.Cleanup(srv.Close)
+
+	path := filepath.Join(t.TempDir(), "config.json")
+	data, _ := json.Marshal(config.Config{APIKey: "test", BaseURL: srv.URL, Model: "fake"})
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	old := config.Path
+	config.Path = path
+	t.Cleanup(func() { config.Path = old })
+}
+
+// recordingCallback 记录收到的输出
+type recordingCallback struct {
+	mu      sync.Mutex
+	content []string
+}
+
+func (c *recordingCallback) OnStart(agentName string) {}
+
+func (c *recordingCallback) OnContent(agentName string, content string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.content = append(c.content, content)
+}
+
+func (c *recordingCallback) OnComplete(agentName string) {}
+
+func (c *recordingCallback) OnRoundComplete(round int, results map[string]string) {}
+
+func (c *recordingCallback) OnAllComplete(allResults []map[string]string) {}
+
+// newTestServer 创建注册了单个Agent（writer）和两个Agent组成的Group（team）的服务
+func newTestServer(t *testing.T, llm *fakeLLM) (*httptest.Server, *recordingCallback) {
+	t.Helper()
+	useFakeLLM(t, llm)
+
+	own := &recordingCallback{}
+	writer := agent.NewAgent("writer", "写作", "负责撰写")
+	writer.SetCallback(own)
+	group := agent.NewGroup(1, true, own)
+	group.AddAgent(agent.NewAgent("a", "领域a", "专家a"))
+	group.AddAgent(agent.NewAgent("b", "领域b", "专家b"))
+
+	s := NewServer()
+	if err := s.RegisterAgent("writer", writer); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.RegisterGroup("team", group); err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(s)
+	t.Cleanup(srv.Close)
+	return srv, own
+}
+
+func postChat(t *testing.T, url, body string) *http.Response {
+	t.Helper()
+	resp, err := http.Post(url+"/v1/chat/completions", "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func TestModels(t *testing.T) {
+	srv, _ := newTestServer(t, &fakeLLM{})
+
+	resp, err := http.Get(srv.URL + "/v1/models")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var list struct {
+		Object string        `json:"object"`
+		Data   []modelObject `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		t.Fatal(err)
+	}
+	if list.Object != "list" || len(list.Data) != 2 {
+		t.Fatalf("unexpected list: %+v", list)
+	}
+	if list.Data[0].ID != "team" || list.Data[0].Kind != KindGroup ||
+		list.Data[1].ID != "writer" || list.Data[1].Kind != KindAgent {
+		t.Errorf("unexpected models: %+v", list.Data)
+	}
+
+	resp, err = http.Get(srv.URL + "/v1/models/writer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("GET /v1/models/writer: status %d", resp.StatusCode)
+	}
+
+	resp, err = http.Get(srv.URL + "/v1/models/missing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("GET /v1/models/missing: status %d, want 404", resp.StatusCode)
+	}
+}
+
+func TestChatCompletions(t *testing.T) {
+	srv, own := newTestServer(t, &fakeLLM{})
+
+	resp := postChat(t, srv.URL, `{"model": "writer", "messages": [
+		{"role": "user", "content": "之前的问题"},
+		{"role": "assistant", "content": "之前的回答"},
+		{"role": "user", "content": "你好"}]}`)
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status %d", resp.StatusCode)
+	}
+	var out oneapi.ChatCompletionResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+	if len(out.Choices) != 1 || out.Choices[0].Message.Content != "reply: 你好" {
+		t.Errorf("unexpected choices: %+v", out.Choices)
+	}
+	if out.Model != "writer" || out.Usage.TotalTokens != tokensPerCall {
+		t.Errorf("model %s, usage %d", out.Model, out.Usage.TotalTokens)
+	}
+	if len(own.content) != 0 {
+		t.Errorf("agent callback received request output: %v", own.content)
+	}
+
+	for body, status := range map[string]int{
+		`{"model": "missing", "messages": [{"role": "user", "content": "你好"}]}`:    http.StatusNotFound,
+		`{"model": "writer", "messages": []}`:                                      http.StatusBadRequest,
+		`{"model": "writer", "messages": [{"role": "assistant", "content": "x"}]}`: http.StatusBadRequest,
+	} {
+		resp := postChat(t, srv.URL, body)
+		resp.Body.Close()
+		if resp.StatusCode != status {
+			t.Errorf("%s: status %d, want %d", body, resp.StatusCode, status)
+		}
+	}
+}
+
+func TestChatCompletionsStream(t *testing.T) {
+	srv, own := newTestServer(t, &fakeLLM{})
+
+	resp := postChat(t, srv.URL, `{"model": "team", "stream": true,
+		"messages": [{"role": "user", "content": "主题"}]}`)
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %s", ct)
+	}
+
+	var chunks []chatCompletionChunk
+	done := false
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		if data == "[DONE]" {
+			done = true
+			break
+		}
+		var chunk chatCompletionChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			t.Fatalf("invalid chunk %s: %v", data, err)
+		}
+		chunks = append(chunks, chunk)
+	}
+	if !done {
+		t.Fatal("stream ended without [DONE]")
+	}
+	if len(chunks) < 4 {
+		t.Fatalf("got %d chunks, want role, two agents and finish", len(chunks))
+	}
+
+	if chunks[0].Choices[0].Delta.Role != "assistant" {
+		t.Errorf("first chunk: %+v", chunks[0])
+	}
+	agents := make(map[string]bool)
+	var content strings.Builder
+	for _, chunk := range chunks[1 : len(chunks)-1] {
+		delta := chunk.Choices[0].Delta
+		agents[delta.Agent] = true
+		content.WriteString(delta.Content)
+	}
+	if !agents["a"] || !agents["b"] {
+		t.Errorf("content from agents %v, want a and b", agents)
+	}
+	if !strings.Contains(content.String(), "[a]: ") || !strings.Contains(content.String(), "[b]: ") {
+		t.Errorf("content not tagged with speakers: %q", content.String())
+	}
+
+	last := chunks[len(chunks)-1]
+	if reason := last.Choices[0].FinishReason; reason == nil || *reason != "stop" {
+		t.Errorf("last chunk finish_reason: %v", reason)
+	}
+	if last.Usage == nil || last.Usage.TotalTokens != 2*tokensPerCall {
+		t.Errorf("last chunk usage: %+v", last.Usage)
+	}
+	if len(own.content) != 0 {
+		t.Errorf("group callback received request output: %v", own.content)
+	}
+}
+
+func TestChatCompletionsConcurrent(t *testing.T) {
+	llm := &fakeLLM{delay: 100 * time.Millisecond}
+	srv, _ := newTestServer(t, llm)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := http.Post(srv.URL+"/v1/chat/completions", "application/json",
+				strings.NewReader(`{"model": "writer", "messages": [{"role": "user", "content": "你好"}]}`))
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Errorf("status %d", resp.StatusCode)
+			}
+		}()
+	}
+	wg.Wait()
+	if got := atomic.LoadInt32(&llm.maxSeen); got != 2 {
+		t.Errorf("max concurrent requests = %d, want 2", got)
+	}
+}
@@ -0,0 +1,224 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"multi-agent/agent"
+	"sort"
+	"sync"
+	"time"
+)
+
+// RunStatus 异步运行的状态
+type RunStatus string
+
+const (
+	RunQueued    RunStatus = "queued"
+	RunRunning   RunStatus = "running"
+	RunSucceeded RunStatus = "succeeded"
+	RunFailed    RunStatus = "failed"
+	RunCancelled RunStatus = "cancelled"
+)
+
+// Finished 是否已结束
+func (s RunStatus) Finished() bool {
+	return s == RunSucceeded || s == RunFailed || s == RunCancelled
+}
+
+// Run 一次异步运行
+type Run struct {
+	ID         string              `json:"id"`
+	Workflow   string              `json:"workflow"`
+	Input      string              `json:"input"`
+	Status     RunStatus           `json:"status"`
+	Error      string              `json:"error,omitempty"`
+	CreatedAt  time.Time           `json:"created_at"`
+	StartedAt  *time.Time          `json:"started_at,omitempty"`
+	FinishedAt *time.Time          `json:"finished_at,omitempty"`
+	Rounds     []agent.RoundResult `json:"rounds,omitempty"` // 已完成轮次的结果
+	// Transcript 运行结束时的运行记录（见 agent.Recorder）
+	Transcript *agent.Transcript `json:"transcript,omitempty"`
+}
+
+// 事件类型
+const (
+	EventStatus        = "status"         // 运行状态变化
+	EventAgentStart    = "agent_start"    // Agent开始输出
+	EventContent       = "content"        // Agent输出内容
+	EventAgentComplete = "agent_complete" // Agent输出完成
+	EventRoundComplete = "round_complete" // 一轮完成
+	EventBlackboard    = "blackboard"     // 黑板上的键发生变化
+	EventInputRequired = "input_required" // 等待人类参与者通过 /v1/runs/{id}/input 回复
+	// 等待通过 /v1/runs/{id}/approvals 批准工具调用
+	EventApprovalRequired = "approval_required"
+	EventApprovalDecided  = "approval_decided" // 工具调用已被批准或拒绝
+)
+
+// Event 运行过程中的事件，Seq 从1开始递增
+type Event struct {
+	Seq     int       `json:"seq"`
+	Type    string    `json:"type"`
+	Time    time.Time `json:"time"`
+	Agent   string    `json:"agent,omitempty"`
+	Content string    `json:"content,omitempty"`
+	Round   int       `json:"round,omitempty"` // 轮次，从1开始
+	Status  RunStatus `json:"status,omitempty"`
+	// Blackboard 黑板变更，仅 blackboard 事件使用
+	Blackboard *agent.BlackboardEvent `json:"blackboard,omitempty"`
+	// Prompt 等待回复的人类输入请求，仅 input_required 事件使用
+	Prompt *agent.HumanPrompt `json:"prompt,omitempty"`
+	// Approval 工具调用的审批请求，仅 approval_required 和 approval_decided 事件使用
+	Approval *agent.ApprovalRequest `json:"approval,omitempty"`
+	// Decision 审批结果，仅 approval_decided 事件使用
+	Decision *agent.ApprovalDecision `json:"decision,omitempty"`
+}
+
+// RunStore 运行状态存储接口
+type RunStore interface {
+	// Save 保存运行，已存在时覆盖
+	Save(run *Run) error
+	// Get 获取运行，不存在时返回 ErrRunNotFound
+	Get(id string) (*Run, error)
+	// List 按创建时间返回所有运行
+	List() ([]*Run, error)
+	// AppendEvent 追加事件，由存储分配 Seq
+	AppendEvent(id string, event Event) (Event, error)
+	// Events 返回 Seq 大于 after 的事件
+	Events(id string, after int) ([]Event, error)
+}
+
+// DefaultMaxRuns MemoryRunStore 默认最多保留的已结束运行数
+const DefaultMaxRuns = 1000
+
+// MemoryRunStore 基于内存的运行存储，已结束的运行超过上限时删除最早创建的
+type MemoryRunStore struct {
+	runs     map[string][]byte
+	events   map[string][]Event
+	order    []string        // 按保存顺序排列的运行ID
+	finished map[string]bool // 已结束的运行
+	maxRuns  int             // 最多保留的已结束运行数，0表示不限制
+	mu       sync.RWMutex
+}
+
+// NewMemoryRunStore 创建内存运行存储，最多保留 DefaultMaxRuns 个已结束的运行
+func NewMemoryRunStore() *MemoryRunStore {
+	return &MemoryRunStore{
+		runs:     make(map[string][]byte),
+		events:   make(map[string][]Event),
+		finished: make(map[string]bool),
+		maxRuns:  DefaultMaxRuns,
+	}
+}
+
+// SetMaxRuns 设置最多保留的已结束运行数，超出时删除最早的运行及其事件；
+// 排队中和执行中的运行不会被删除。n小于等于0表示不限制
+func (s *MemoryRunStore) SetMaxRuns(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if n < 0 {
+		n = 0
+	}
+	s.maxRuns = n
+	s.evictLocked()
+}
+
+func (s *MemoryRunStore) Save(run *Run) error {
+	// 序列化保存，避免与运行中的数据共享引用
+	data, err := json.Marshal(run)
+	if err != nil {
+		return fmt.Errorf("marshal run failed: %w", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.runs[run.ID]; !ok {
+		s.order = append(s.order, run.ID)
+	}
+	s.runs[run.ID] = data
+	if run.Status.Finished() {
+		s.finished[run.ID] = true
+		s.evictLocked()
+	}
+	return nil
+}
+
+// evictLocked 删除超出上限的最早的已结束运行，调用方需持有 s.mu
+func (s *MemoryRunStore) evictLocked() {
+	if s.maxRuns <= 0 || len(s.finished) <= s.maxRuns {
+		return
+	}
+	excess := len(s.finished) - s.maxRuns
+	kept := s.order[:0]
+	for _, id := range s.order {
+		if excess > 0 && s.finished[id] {
+			delete(s.runs, id)
+			delete(s.events, id)
+			delete(s.finished, id)
+			excess--
+			continue
+		}
+		kept = append(kept, id)
+	}
+	s.order = kept
+}
+
+func (s *MemoryRunStore) Get(id string) (*Run, error) {
+	s.mu.RLock()
+	data, ok := s.runs[id]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrRunNotFound, id)
+	}
+	return decodeRun(data)
+}
+
+func (s *MemoryRunStore) List() ([]*Run, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	runs := make([]*Run, 0, len(s.runs))
+	for _, data := range s.runs {
+		run, err := decodeRun(data)
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, run)
+	}
+	sort.Slice(runs, func(i, j int) bool {
+		return runs[i].CreatedAt.Before(runs[j].CreatedAt)
+	})
+	return runs, nil
+}
+
+func (s *MemoryRunStore) AppendEvent(id string, event Event) (Event, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.runs[id]; !ok {
+		return event, fmt.Errorf("%w: %s", ErrRunNotFound, id)
+	}
+	event.Seq = len(s.events[id]) + 1
+	s.events[id] = append(s.events[id], event)
+	return event, nil
+}
+
+func (s *MemoryRunStore) Events(id string, after int) ([]Event, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if _, ok := s.runs[id]; !ok {
+		return nil, fmt.Errorf("%w: %s", ErrRunNotFound, id)
+	}
+	events := s.events[id]
+	if after < 0 {
+		after = 0
+	}
+	if after >= len(events) {
+		return nil, nil
+	}
+	return append([]Event(nil), events[after:]...), nil
+}
+
+func decodeRun(data []byte) (*Run, error) {
+	var run Run
+	if err := json.Unmarshal(data, &run); err != nil {
+		return nil, fmt.Errorf("decode run failed: %w", err)
+	}
+	return &run, nil
+}
@@ -0,0 +1,743 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"multi-agent/agent"
+	"multi-agent/workflow"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// keepAliveInterval 事件流空闲时发送心跳的间隔
+const keepAliveInterval = 15 * time.Second
+
+// RunManager 管理异步运行：提交后进入有界队列，由固定数量的worker执行
+type RunManager struct {
+	store     RunStore
+	reg       *workflow.Registry
+	queue     chan *activeRun
+	workflows map[string]*workflow.Spec
+	active    map[string]*activeRun // 排队中和执行中的运行
+	mu        sync.Mutex
+	wg        sync.WaitGroup
+	closed    bool
+	seq       uint64
+	inputs    *agent.ChannelInput    // 运行中人类参与者的输入，通过 /v1/runs/{id}/input 回复
+	approvals *agent.ChannelApprover // 运行中工具调用的审批，通过 /v1/runs/{id}/approvals 回复
+	// allowDefinitions 是否接受内联的工作流定义
+	allowDefinitions bool
+}
+
+// activeRun 尚未结束的运行
+type activeRun struct {
+	id     string
+	spec   *workflow.Spec
+	input  string
+	status RunStatus
+	ctx    context.Context
+	cancel context.CancelFunc
+	notify chan struct{} // 有新事件时关闭并替换
+	// 运行所在的会话及其运行记录器
+	session  *agent.Session
+	recorder *agent.Recorder
+}
+
+// NewRunManager 创建运行管理器并启动worker。workers为并发执行数，queueSize为最多排队的运行数
+func NewRunManager(store RunStore, reg *workflow.Registry, workers, queueSize int) *RunManager {
+	if store == nil {
+		store = NewMemoryRunStore()
+	}
+	if reg == nil {
+		reg = workflow.NewRegistry()
+	}
+	if workers < 1 {
+		workers = 1
+	}
+	if queueSize < 1 {
+		queueSize = 1
+	}
+	m := &RunManager{
+		store:     store,
+		reg:       reg,
+		queue:     make(chan *activeRun, queueSize),
+		workflows: make(map[string]*workflow.Spec),
+		active:    make(map[string]*activeRun),
+		inputs:    agent.NewChannelInput(0),
+		approvals: agent.NewChannelApprover(0),
+	}
+	m.inputs.SetNotify(m.inputRequired)
+	m.approvals.SetNotify(m.approvalRequired)
+	for i := 0; i < workers; i++ {
+		m.wg.Add(1)
+		go m.worker()
+	}
+	return m
+}
+
+// RegisterWorkflow 注册可按名称提交的工作流
+func (m *RunManager) RegisterWorkflow(name string, spec *workflow.Spec) error {
+	if err := spec.Validate(m.reg); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.workflows[name] = spec
+	return nil
+}
+
+// AllowDefinitions 设置是否接受内联的工作流定义，默认不接受。
+// 内联定义可以使用注册表中的任意工具，只应在可信的调用方之间开启
+func (m *RunManager) AllowDefinitions(allow bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.allowDefinitions = allow
+}
+
+// Submit 提交运行。workflowName 为已注册的工作流名称；definition 不为空时使用内联的YAML/JSON定义，
+// 需要先通过 AllowDefinitions 开启
+func (m *RunManager) Submit(workflowName, definition, input string) (*Run, error) {
+	if strings.TrimSpace(input) == "" {
+		return nil, fmt.Errorf("%w: input is empty", ErrInvalidRequest)
+	}
+
+	var spec *workflow.Spec
+	if definition != "" {
+		m.mu.Lock()
+		allow := m.allowDefinitions
+		m.mu.Unlock()
+		if !allow {
+			return nil, ErrDefinitionDenied
+		}
+		parsed, err := workflow.Parse([]byte(definition), "definition")
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidRequest, err)
+		}
+		if err := parsed.Validate(m.reg); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidRequest, err)
+		}
+		spec = parsed
+		if workflowName == "" {
+			workflowName = spec.Name
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return nil, ErrManagerClosed
+	}
+	if spec == nil {
+		spec = m.workflows[workflowName]
+		if spec == nil {
+			return nil, fmt.Errorf("%w: %s", ErrWorkflowNotFound, workflowName)
+		}
+	}
+	if len(m.queue) == cap(m.queue) {
+		return nil, ErrQueueFull
+	}
+
+	run := &Run{
+		ID:        fmt.Sprintf("run_%d_%d", time.Now().UnixNano(), atomic.AddUint64(&m.seq, 1)),
+		Workflow:  workflowName,
+		Input:     input,
+		Status:    RunQueued,
+		CreatedAt: time.Now(),
+	}
+	if err := m.store.Save(run); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ar := &activeRun{
+		id:       run.ID,
+		spec:     spec,
+		input:    input,
+		status:   RunQueued,
+		ctx:      ctx,
+		cancel:   cancel,
+		notify:   make(chan struct{}),
+		session:  agent.NewSession(run.ID),
+		recorder: agent.NewRecorder(),
+	}
+	ar.recorder.SetWorkflow(workflowName)
+	ar.session.SetRecorder(ar.recorder)
+	m.active[run.ID] = ar
+	m.appendEventLocked(ar, Event{Type: EventStatus, Status: RunQueued})
+	// 持有锁且已确认队列未满，发送不会阻塞
+	m.queue <- ar
+	return run, nil
+}
+
+// Get 获取运行状态
+func (m *RunManager) Get(id string) (*Run, error) {
+	return m.store.Get(id)
+}
+
+// List 返回所有运行
+func (m *RunManager) List() ([]*Run, error) {
+	return m.store.List()
+}
+
+// Events 返回 Seq 大于 after 的事件
+func (m *RunManager) Events(id string, after int) ([]Event, error) {
+	return m.store.Events(id, after)
+}
+
+// Transcript 返回运行记录，未结束的运行返回到目前为止的记录
+func (m *RunManager) Transcript(id string) (*agent.Transcript, error) {
+	m.mu.Lock()
+	ar, ok := m.active[id]
+	m.mu.Unlock()
+	if ok {
+		return ar.recorder.Transcript(ar.session), nil
+	}
+	run, err := m.store.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if run.Transcript == nil {
+		return nil, fmt.Errorf("%w: run %s has no transcript", ErrRunNotFound, id)
+	}
+	return run.Transcript, nil
+}
+
+// Cancel 取消排队中或执行中的运行
+func (m *RunManager) Cancel(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ar, ok := m.active[id]
+	if !ok {
+		run, err := m.store.Get(id)
+		if err != nil {
+			return err
+		}
+		return fmt.Errorf("%w: %s is %s", ErrRunFinished, id, run.Status)
+	}
+
+	ar.cancel()
+	if ar.status == RunQueued {
+		// 排队中的运行直接结束，worker取到时会跳过
+		m.finishLocked(ar, RunCancelled, nil, context.Canceled)
+	}
+	return nil
+}
+
+// Close 停止接收新运行，取消所有未结束的运行并等待worker退出
+func (m *RunManager) Close() {
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return
+	}
+	m.closed = true
+	for _, ar := range m.active {
+		ar.cancel()
+	}
+	close(m.queue)
+	m.mu.Unlock()
+	m.wg.Wait()
+}
+
+// HumanInput 返回运行中人类参与者的输入来源，请求的会话ID即运行ID
+func (m *RunManager) HumanInput() *agent.ChannelInput {
+	return m.inputs
+}
+
+// Approver 返回运行中工具调用的审批者，请求的会话ID即运行ID
+func (m *RunManager) Approver() *agent.ChannelApprover {
+	return m.approvals
+}
+
+// Decide 回复运行中等待批准的工具调用，approvalID 为空时回复最早的请求
+func (m *RunManager) Decide(id, approvalID string, decision agent.ApprovalDecision) error {
+	if _, err := m.Get(id); err != nil {
+		return err
+	}
+	pending := m.approvals.Pending(id)
+	if approvalID == "" && len(pending) > 0 {
+		approvalID = pending[0].ID
+	}
+	for _, p := range pending {
+		if p.ID == approvalID && m.approvals.Decide(approvalID, decision) {
+			m.mu.Lock()
+			if ar, ok := m.active[id]; ok {
+				m.appendEventLocked(ar, Event{Type: EventApprovalDecided, Agent: p.Agent, Approval: &p, Decision: &decision})
+			}
+			m.mu.Unlock()
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: run %s has no pending approval %q", ErrApprovalNotFound, id, approvalID)
+}
+
+// Reply 回复运行中等待人类参与者发言的请求，inputID 为空时回复最早的请求
+func (m *RunManager) Reply(id, inputID, content string) error {
+	if _, err := m.Get(id); err != nil {
+		return err
+	}
+	pending := m.inputs.Pending(id)
+	if inputID == "" && len(pending) > 0 {
+		inputID = pending[0].ID
+	}
+	for _, p := range pending {
+		if p.ID == inputID && m.inputs.Reply(inputID, content) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: run %s has no pending input %q", ErrInputNotFound, id, inputID)
+}
+
+// inputRequired 记录等待人类参与者回复的事件
+func (m *RunManager) inputRequired(prompt agent.HumanPrompt) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if ar, ok := m.active[prompt.SessionID]; ok {
+		m.appendEventLocked(ar, Event{Type: EventInputRequired, Agent: prompt.Agent, Content: prompt.Input, Prompt: &prompt})
+	}
+}
+
+// approvalRequired 记录等待批准工具调用的事件
+func (m *RunManager) approvalRequired(req agent.ApprovalRequest) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if ar, ok := m.active[req.SessionID]; ok {
+		m.appendEventLocked(ar, Event{Type: EventApprovalRequired, Agent: req.Agent, Content: req.Tool, Approval: &req})
+	}
+}
+
+// watch 返回运行的事件通知通道，运行已结束时返回nil
+func (m *RunManager) watch(id string) <-chan struct{} {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if ar, ok := m.active[id]; ok {
+		return ar.notify
+	}
+	return nil
+}
+
+func (m *RunManager) worker() {
+	defer m.wg.Done()
+	for ar := range m.queue {
+		m.execute(ar)
+	}
+}
+
+// execute 执行一次运行
+func (m *RunManager) execute(ar *activeRun) {
+	m.mu.Lock()
+	if ar.status != RunQueued {
+		m.mu.Unlock()
+		return
+	}
+	if ar.ctx.Err() != nil {
+		m.finishLocked(ar, RunCancelled, nil, ar.ctx.Err())
+		m.mu.Unlock()
+		return
+	}
+	ar.status = RunRunning
+	m.updateLocked(ar.id, func(run *Run) {
+		now := time.Now()
+		run.Status = RunRunning
+		run.StartedAt = &now
+	})
+	m.appendEventLocked(ar, Event{Type: EventStatus, Status: RunRunning})
+	m.mu.Unlock()
+
+	// 人类参与者和工具审批通过运行接口进行
+	reg := *m.reg
+	reg.SetHumanInput(m.inputs)
+	reg.SetApprover(m.approvals)
+	wf, err := workflow.Build(ar.spec, &reg)
+	var rounds []agent.RoundResult
+	if err == nil {
+		ctx := agent.WithCallback(agent.WithSession(ar.ctx, ar.session), &runCallback{manager: m, run: ar})
+		ar.session.Blackboard().Watch("", func(e agent.BlackboardEvent) {
+			m.appendEvent(ar, Event{Type: EventBlackboard, Agent: e.Author, Blackboard: &e})
+		})
+		rounds, err = wf.Execute(ctx, ar.input)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	switch {
+	case ar.ctx.Err() != nil:
+		m.finishLocked(ar, RunCancelled, rounds, ar.ctx.Err())
+	case err != nil:
+		m.finishLocked(ar, RunFailed, rounds, err)
+	default:
+		m.finishLocked(ar, RunSucceeded, rounds, nil)
+	}
+}
+
+// finishLocked 记录运行结束，调用方需持有 m.mu
+func (m *RunManager) finishLocked(ar *activeRun, status RunStatus, rounds []agent.RoundResult, err error) {
+	ar.status = status
+	m.updateLocked(ar.id, func(run *Run) {
+		now := time.Now()
+		run.Status = status
+		run.FinishedAt = &now
+		run.Rounds = rounds
+		run.Transcript = ar.recorder.Transcript(ar.session)
+		if err != nil {
+			run.Error = err.Error()
+		}
+	})
+	m.appendEventLocked(ar, Event{Type: EventStatus, Status: status})
+	delete(m.active, ar.id)
+	ar.cancel()
+}
+
+// updateLocked 读取、修改并保存运行，调用方需持有 m.mu
+func (m *RunManager) updateLocked(id string, update func(run *Run)) {
+	run, err := m.store.Get(id)
+	if err != nil {
+		log.Printf("更新运行 %s 失败: %v", id, err)
+		return
+	}
+	update(run)
+	if err := m.store.Save(run); err != nil {
+		log.Printf("保存运行 %s 失败: %v", id, err)
+	}
+}
+
+// appendEvent 追加事件并通知订阅者
+func (m *RunManager) appendEvent(ar *activeRun, event Event) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.appendEventLocked(ar, event)
+}
+
+func (m *RunManager) appendEventLocked(ar *activeRun, event Event) {
+	event.Time = time.Now()
+	if _, err := m.store.AppendEvent(ar.id, event); err != nil {
+		log.Printf("保存运行 %s 的事件失败: %v", ar.id, err)
+		return
+	}
+	close(ar.notify)
+	ar.notify = make(chan struct{})
+}
+
+// runCallback 将运行输出记录为事件
+type runCallback struct {
+	manager *RunManager
+	run     *activeRun
+}
+
+func (c *runCallback) OnStart(agentName string) {
+	c.manager.appendEvent(c.run, Event{Type: EventAgentStart, Agent: agentName})
+}
+
+func (c *runCallback) OnContent(agentName string, content string) {
+	c.manager.appendEvent(c.run, Event{Type: EventContent, Agent: agentName, Content: content})
+}
+
+func (c *runCallback) OnComplete(agentName string) {
+	c.manager.appendEvent(c.run, Event{Type: EventAgentComplete, Agent: agentName})
+}
+
+func (c *runCallback) OnRoundComplete(round int, results map[string]string) {
+	c.manager.appendEvent(c.run, Event{Type: EventRoundComplete, Round: round + 1})
+}
+
+func (c *runCallback) OnAllComplete(allResults []map[string]string) {}
+
+// ServeHTTP 提供运行管理接口：
+//
+//	POST /v1/runs                   提交运行
+//	GET  /v1/runs                   列出运行
+//	GET  /v1/runs/{id}              查询状态
+//	GET  /v1/runs/{id}/events       SSE事件流，支持 Last-Event-ID 续传
+//	POST /v1/runs/{id}/cancel       取消运行
+//	GET  /v1/runs/{id}/result       最终轮次结果
+//	GET  /v1/runs/{id}/transcript   运行记录（agent.Transcript）
+//	GET  /v1/runs/{id}/input        等待人类参与者回复的请求
+//	POST /v1/runs/{id}/input        回复人类参与者的发言
+//	GET  /v1/runs/{id}/approvals    等待批准的工具调用
+//	POST /v1/runs/{id}/approvals    批准或拒绝工具调用
+func (m *RunManager) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/v1/runs"), "/")
+	if path == "" {
+		switch r.Method {
+		case http.MethodPost:
+			m.handleSubmit(w, r)
+		case http.MethodGet:
+			m.handleList(w, r)
+		default:
+			writeError(w, http.StatusMethodNotAllowed, "invalid_request_error", "method not allowed")
+		}
+		return
+	}
+
+	id, action, _ := strings.Cut(path, "/")
+	method := http.MethodGet
+	if action == "cancel" || (action == "input" || action == "approvals") && r.Method == http.MethodPost {
+		method = http.MethodPost
+	}
+	if r.Method != method {
+		writeError(w, http.StatusMethodNotAllowed, "invalid_request_error", "method not allowed")
+		return
+	}
+
+	switch action {
+	case "":
+		m.handleGet(w, id)
+	case "events":
+		m.handleEvents(w, r, id)
+	case "cancel":
+		m.handleCancel(w, id)
+	case "result":
+		m.handleResult(w, id)
+	case "transcript":
+		m.handleTranscript(w, id)
+	case "input":
+		m.handleInput(w, r, id)
+	case "approvals":
+		m.handleApprovals(w, r, id)
+	default:
+		writeError(w, http.StatusNotFound, "not_found", fmt.Sprintf("unknown path %s", r.URL.Path))
+	}
+}
+
+// submitRequest 提交运行的请求
+type submitRequest struct {
+	Workflow   string `json:"workflow"`   // 已注册的工作流名称
+	Definition string `json:"definition"` // 内联的YAML/JSON工作流定义
+	Input      string `json:"input"`
+}
+
+func (m *RunManager) handleSubmit(w http.ResponseWriter, r *http.Request) {
+	var req submitRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", fmt.Sprintf("invalid request body: %v", err))
+		return
+	}
+	run, err := m.Submit(req.Workflow, req.Definition, req.Input)
+	if err != nil {
+		writeRunError(w, err)
+		return
+	}
+	w.Header().Set("Location", "/v1/runs/"+run.ID)
+	writeJSON(w, http.StatusAccepted, run)
+}
+
+func (m *RunManager) handleList(w http.ResponseWriter, r *http.Request) {
+	runs, err := m.List()
+	if err != nil {
+		writeRunError(w, err)
+		return
+	}
+	// 列表中不返回结果和运行记录，避免响应过大
+	for _, run := range runs {
+		run.Rounds, run.Transcript = nil, nil
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"object": "list",
+		"data":   runs,
+	})
+}
+
+func (m *RunManager) handleGet(w http.ResponseWriter, id string) {
+	run, err := m.Get(id)
+	if err != nil {
+		writeRunError(w, err)
+		return
+	}
+	run.Rounds, run.Transcript = nil, nil
+	writeJSON(w, http.StatusOK, run)
+}
+
+func (m *RunManager) handleCancel(w http.ResponseWriter, id string) {
+	if err := m.Cancel(id); err != nil {
+		writeRunError(w, err)
+		return
+	}
+	run, err := m.Get(id)
+	if err != nil {
+		writeRunError(w, err)
+		return
+	}
+	run.Rounds, run.Transcript = nil, nil
+	writeJSON(w, http.StatusAccepted, run)
+}
+
+// runResult 运行结果，格式与 ambergen run --json 一致，可直接用于 replay
+type runResult struct {
+	Workflow string              `json:"workflow"`
+	Input    string              `json:"input"`
+	RunID    string              `json:"run_id"`
+	Status   RunStatus           `json:"status"`
+	Error    string              `json:"error,omitempty"`
+	Rounds   []agent.RoundResult `json:"rounds"`
+}
+
+func (m *RunManager) handleResult(w http.ResponseWriter, id string) {
+	run, err := m.Get(id)
+	if err != nil {
+		writeRunError(w, err)
+		return
+	}
+	if !run.Status.Finished() {
+		writeError(w, http.StatusConflict, "run_not_finished", fmt.Sprintf("run %s is %s", id, run.Status))
+		return
+	}
+	writeJSON(w, http.StatusOK, runResult{
+		Workflow: run.Workflow,
+		Input:    run.Input,
+		RunID:    run.ID,
+		Status:   run.Status,
+		Error:    run.Error,
+		Rounds:   run.Rounds,
+	})
+}
+
+// handleTranscript 返回运行记录，格式与 ambergen run --save 相同，可用于 replay、diff 和 chat --transcript
+func (m *RunManager) handleTranscript(w http.ResponseWriter, id string) {
+	transcript, err := m.Transcript(id)
+	if err != nil {
+		writeRunError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, transcript)
+}
+
+// inputReply 回复人类参与者发言的请求
+type inputReply struct {
+	ID      string `json:"id"` // 输入请求ID，为空时回复最早的请求
+	Content string `json:"content"`
+}
+
+func (m *RunManager) handleInput(w http.ResponseWriter, r *http.Request, id string) {
+	if r.Method == http.MethodGet {
+		if _, err := m.Get(id); err != nil {
+			writeRunError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"object": "list",
+			"data":   m.inputs.Pending(id),
+		})
+		return
+	}
+
+	var req inputReply
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", fmt.Sprintf("invalid request body: %v", err))
+		return
+	}
+	if err := m.Reply(id, req.ID, req.Content); err != nil {
+		writeRunError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// approvalReply 批准或拒绝工具调用的请求
+type approvalReply struct {
+	ID       string `json:"id"` // 审批请求ID，为空时回复最早的请求
+	Approved bool   `json:"approved"`
+	Reason   string `json:"reason"` // 拒绝原因，会返回给模型
+}
+
+func (m *RunManager) handleApprovals(w http.ResponseWriter, r *http.Request, id string) {
+	if r.Method == http.MethodGet {
+		if _, err := m.Get(id); err != nil {
+			writeRunError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"object": "list",
+			"data":   m.approvals.Pending(id),
+		})
+		return
+	}
+
+	var req approvalReply
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", fmt.Sprintf("invalid request body: %v", err))
+		return
+	}
+	if err := m.Decide(id, req.ID, agent.ApprovalDecision{Approved: req.Approved, Reason: req.Reason}); err != nil {
+		writeRunError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (m *RunManager) handleEvents(w http.ResponseWriter, r *http.Request, id string) {
+	if _, err := m.Get(id); err != nil {
+		writeRunError(w, err)
+		return
+	}
+	after := 0
+	if v := r.Header.Get("Last-Event-ID"); v != "" {
+		after, _ = strconv.Atoi(v)
+	} else if v := r.URL.Query().Get("after"); v != "" {
+		after, _ = strconv.Atoi(v)
+	}
+
+	stream, err := newSSEStream(w)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+		return
+	}
+
+	for {
+		// 先获取通知通道再读取事件，避免遗漏两者之间产生的事件
+		notify := m.watch(id)
+		events, err := m.Events(id, after)
+		if err != nil {
+			stream.event(newErrorResponse("server_error", err.Error()))
+			return
+		}
+		for _, event := range events {
+			stream.namedEvent(event.Seq, event.Type, event)
+			after = event.Seq
+		}
+		if notify == nil {
+			stream.done()
+			return
+		}
+
+		select {
+		case <-notify:
+		case <-r.Context().Done():
+			return
+		case <-time.After(keepAliveInterval):
+			stream.keepAlive()
+		}
+	}
+}
+
+// writeRunError 根据错误类型返回对应的状态码
+func writeRunError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrRunNotFound):
+		writeError(w, http.StatusNotFound, "run_not_found", err.Error())
+	case errors.Is(err, ErrInputNotFound):
+		writeError(w, http.StatusNotFound, "input_not_found", err.Error())
+	case errors.Is(err, ErrApprovalNotFound):
+		writeError(w, http.StatusNotFound, "approval_not_found", err.Error())
+	case errors.Is(err, ErrWorkflowNotFound):
+		writeError(w, http.StatusNotFound, "workflow_not_found", err.Error())
+	case errors.Is(err, ErrDefinitionDenied):
+		writeError(w, http.StatusForbidden, "definitions_disabled", err.Error())
+	case errors.Is(err, ErrInvalidRequest):
+		writeError(w, http.StatusBadRequest, "invalid_request_error", err.Error())
+	case errors.Is(err, ErrRunFinished):
+		writeError(w, http.StatusConflict, "run_finished", err.Error())
+	case errors.Is(err, ErrQueueFull), errors.Is(err, ErrManagerClosed):
+		writeError(w, http.StatusServiceUnavailable, "queue_full", err.Error())
+	default:
+		writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+	}
+}
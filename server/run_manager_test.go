@@ -0,0 +1,337 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"multi-agent/agent"
+	"multi-agent/tools"
+	"multi-agent/workflow"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testWorkflow = `version: 1
+name: solo
+agents:
+  - name: writer
+    expertise: writing
+    description: 负责撰写
+group:
+  rounds: 1
+`
+
+// newTestRunManager 创建注册了 solo 工作流的运行管理器
+func newTestRunManager(t *testing.T) (*RunManager, *httptest.Server) {
+	t.Helper()
+	useFakeLLM(t, &fakeLLM{})
+	spec, err := workflow.Parse([]byte(testWorkflow), "solo.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := NewRunManager(nil, nil, 1, 10)
+	t.Cleanup(m.Close)
+	if err := m.RegisterWorkflow("solo", spec); err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(m)
+	t.Cleanup(srv.Close)
+	return m, srv
+}
+
+// waitFinished 等待运行结束
+func waitFinished(t *testing.T, m *RunManager, id string) *Run {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		run, err := m.Get(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if run.Status.Finished() {
+			return run
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("run %s did not finish", id)
+	return nil
+}
+
+func TestRunTranscript(t *testing.T) {
+	m, srv := newTestRunManager(t)
+
+	run, err := m.Submit("solo", "", "主题")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if finished := waitFinished(t, m, run.ID); finished.Status != RunSucceeded {
+		t.Fatalf("run %s: %s", finished.Status, finished.Error)
+	}
+
+	resp, err := http.Get(srv.URL + "/v1/runs/" + run.ID + "/transcript")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status %d", resp.StatusCode)
+	}
+	var transcript agent.Transcript
+	if err := json.NewDecoder(resp.Body).Decode(&transcript); err != nil {
+		t.Fatal(err)
+	}
+	if transcript.SessionID != run.ID || transcript.Workflow != "solo" || transcript.Input != "主题" {
+		t.Errorf("unexpected transcript header: %+v", transcript)
+	}
+	if len(transcript.Rounds) != 1 || transcript.Rounds[0]["writer"] == nil {
+		t.Errorf("unexpected rounds: %+v", transcript.Rounds)
+	}
+	if len(transcript.AgentEvents("writer")) == 0 {
+		t.Error("transcript has no messages from writer")
+	}
+
+	// 状态接口不返回运行记录
+	resp, err = http.Get(srv.URL + "/v1/runs/" + run.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var got Run
+	if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Transcript != nil {
+		t.Error("GET /v1/runs/{id} returned the transcript")
+	}
+}
+
+func TestRunDefinitionsDisabledByDefault(t *testing.T) {
+	m, srv := newTestRunManager(t)
+
+	body, _ := json.Marshal(submitRequest{Definition: testWorkflow, Input: "主题"})
+	resp, err := http.Post(srv.URL+"/v1/runs", "application/json", strings.NewReader(string(body)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("status %d, want 403", resp.StatusCode)
+	}
+
+	m.AllowDefinitions(true)
+	run, err := m.Submit("", testWorkflow, "主题")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.Workflow != "solo" {
+		t.Errorf("workflow = %s, want solo", run.Workflow)
+	}
+	waitFinished(t, m, run.ID)
+}
+
+func TestMemoryRunStoreMaxRuns(t *testing.T) {
+	s := NewMemoryRunStore()
+	s.SetMaxRuns(2)
+
+	created := time.Now()
+	save := func(id string, status RunStatus) {
+		t.Helper()
+		if err := s.Save(&Run{ID: id, Status: status, CreatedAt: created}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.AppendEvent(id, Event{Type: EventStatus, Status: status}); err != nil {
+			t.Fatal(err)
+		}
+		created = created.Add(time.Second)
+	}
+	save("running", RunRunning)
+	for i := 1; i <= 3; i++ {
+		save(fmt.Sprintf("done%d", i), RunSucceeded)
+	}
+
+	if _, err := s.Get("done1"); !errors.Is(err, ErrRunNotFound) {
+		t.Errorf("oldest finished run not evicted: %v", err)
+	}
+	if _, err := s.Events("done1", 0); !errors.Is(err, ErrRunNotFound) {
+		t.Errorf("events of evicted run kept: %v", err)
+	}
+	runs, err := s.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, run := range runs {
+		ids = append(ids, run.ID)
+	}
+	if got := strings.Join(ids, ","); got != "running,done2,done3" {
+		t.Errorf("runs = %s, want running,done2,done3", got)
+	}
+}
+
+func TestRunApprovals(t *testing.T) {
+	useFakeLLM(t, &fakeLLM{})
+	spec, err := workflow.Parse([]byte(`version: 1
+name: calc
+agents:
+  - name: writer
+    expertise: math
+    description: 负责计算
+    tools: [calculator]
+    approve_tools: [calculator]
+group:
+  rounds: 1
+`), "calc.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	reg := workflow.NewRegistry()
+	reg.RegisterTool(tools.NewCalculator())
+	m := NewRunManager(nil, reg, 1, 10)
+	defer m.Close()
+	if err := m.RegisterWorkflow("calc", spec); err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(m)
+	defer srv.Close()
+
+	run, err := m.Submit("calc", "", "1+2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 等待审批请求出现在接口中
+	var pending struct {
+		Data []agent.ApprovalRequest `json:"data"`
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for len(pending.Data) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("no pending approval")
+		}
+		time.Sleep(10 * time.Millisecond)
+		resp, err := http.Get(srv.URL + "/v1/runs/" + run.ID + "/approvals")
+		if err != nil {
+			t.Fatal(err)
+		}
+		json.NewDecoder(resp.Body).Decode(&pending)
+		resp.Body.Close()
+	}
+	if p := pending.Data[0]; p.Tool != "calculator" || p.Agent != "writer" || p.SessionID != run.ID {
+		t.Errorf("unexpected approval request: %+v", p)
+	}
+
+	body := fmt.Sprintf(`{"id": %q, "approved": false, "reason": "不允许计算"}`, pending.Data[0].ID)
+	resp, err := http.Post(srv.URL+"/v1/runs/"+run.ID+"/approvals", "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("decide: status %d", resp.StatusCode)
+	}
+	// 已回复的请求不能再次回复
+	resp, err = http.Post(srv.URL+"/v1/runs/"+run.ID+"/approvals", "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("second decide: status %d, want 404", resp.StatusCode)
+	}
+
+	if finished := waitFinished(t, m, run.ID); finished.Status != RunSucceeded {
+		t.Fatalf("run %s: %s", finished.Status, finished.Error)
+	}
+	events, err := m.Events(run.ID, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var types []string
+	for _, e := range events {
+		if e.Type == EventApprovalRequired || e.Type == EventApprovalDecided {
+			types = append(types, e.Type)
+		}
+		if e.Type == EventApprovalDecided && (e.Decision == nil || e.Decision.Approved || e.Decision.Reason != "不允许计算") {
+			t.Errorf("unexpected decision event: %+v", e)
+		}
+	}
+	if got := strings.Join(types, ","); got != "approval_required,approval_decided" {
+		t.Errorf("approval events = %s", got)
+	}
+
+	transcript, err := m.Transcript(run.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	denied := false
+	for _, e := range transcript.AgentEvents("writer") {
+		if e.Message != nil && e.Message.Role == "tool" && strings.Contains(e.Message.Content, "不允许计算") {
+			denied = true
+		}
+	}
+	if !denied {
+		t.Error("denial reason was not returned to the model")
+	}
+}
+
+func TestRunInputReplyOnce(t *testing.T) {
+	useFakeLLM(t, &fakeLLM{})
+	spec, err := workflow.Parse([]byte(`version: 1
+name: meeting
+agents:
+  - name: alice
+    expertise: product
+    human: true
+  - name: writer
+    expertise: writing
+group:
+  rounds: 1
+`), "meeting.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := NewRunManager(nil, nil, 1, 10)
+	defer m.Close()
+	if err := m.RegisterWorkflow("meeting", spec); err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(m)
+	defer srv.Close()
+
+	run, err := m.Submit("meeting", "", "主题")
+	if err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for len(m.HumanInput().Pending(run.ID)) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("no pending input")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	body := fmt.Sprintf(`{"id": %q, "content": "我的意见"}`, m.HumanInput().Pending(run.ID)[0].ID)
+	for i, want := range []int{http.StatusNoContent, http.StatusNotFound} {
+		resp, err := http.Post(srv.URL+"/v1/runs/"+run.ID+"/input", "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Errorf("reply %d: status %d, want %d", i+1, resp.StatusCode, want)
+		}
+	}
+	if finished := waitFinished(t, m, run.ID); finished.Status != RunSucceeded {
+		t.Fatalf("run %s: %s", finished.Status, finished.Error)
+	}
+	transcript, err := m.Transcript(run.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := transcript.Rounds[0]["alice"]; got == nil || got.Content != "我的意见" {
+		t.Errorf("alice = %+v, want the first reply", got)
+	}
+}
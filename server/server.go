@@ -0,0 +1,249 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"multi-agent/agent"
+	"multi-agent/oneapi"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 模型类型
+const (
+	KindAgent = "agent"
+	KindGroup = "group"
+	KindGraph = "graph"
+)
+
+// model 以模型名称对外暴露的Agent、Group或依赖图
+type model struct {
+	name    string
+	kind    string
+	created int64
+	agents  func() []agent.Agent
+	// execute 执行一次请求，返回最终回答和Token用量
+	execute func(ctx context.Context, input string) (string, oneapi.Usage, error)
+}
+
+// Server OpenAI兼容的HTTP服务，将Agent、Group和依赖图作为模型提供
+//
+// 每个请求在独立的会话中执行，记忆由请求中的历史消息构成；输出回调通过上下文（agent.WithCallback）
+// 按请求传递，不修改注册的Agent，因此多个请求可以同时执行。
+type Server struct {
+	mu     sync.RWMutex
+	models map[string]*model
+	mux    *http.ServeMux
+}
+
+// NewServer 创建服务
+func NewServer() *Server {
+	s := &Server{
+		models: make(map[string]*model),
+		mux:    http.NewServeMux(),
+	}
+	s.mux.HandleFunc("/v1/models", s.handleModels)
+	s.mux.HandleFunc("/v1/models/", s.handleModel)
+	s.mux.HandleFunc("/v1/chat/completions", s.handleChatCompletions)
+	return s
+}
+
+// SetRunManager 挂载运行管理接口（/v1/runs），只能调用一次
+func (s *Server) SetRunManager(m *RunManager) {
+	s.mux.Handle("/v1/runs", m)
+	s.mux.Handle("/v1/runs/", m)
+}
+
+// ServeHTTP 实现 http.Handler，可直接用于 http.ListenAndServe 或 httptest.NewServer
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// RegisterAgent 以指定模型名称注册Agent（如 ExpertAgent 或 HumanAgent）。
+// 请求执行期间不使用Agent自身的输出回调
+func (s *Server) RegisterAgent(name string, a agent.Agent) error {
+	return s.register(&model{
+		name:   name,
+		kind:   KindAgent,
+		agents: func() []agent.Agent { return []agent.Agent{a} },
+		execute: func(ctx context.Context, input string) (string, oneapi.Usage, error) {
+			if expert, ok := a.(*agent.ExpertAgent); ok {
+				return expert.ExecuteWithUsage(ctx, input)
+			}
+			output, err := a.Execute(ctx, input)
+			return output, oneapi.Usage{}, err
+		},
+	})
+}
+
+// RegisterGroup 以指定模型名称注册Group，请求执行期间不使用Group自身的输出回调
+func (s *Server) RegisterGroup(name string, g *agent.Group) error {
+	return s.register(&model{
+		name:   name,
+		kind:   KindGroup,
+		agents: g.Agents,
+		execute: func(ctx context.Context, input string) (string, oneapi.Usage, error) {
+			rounds, err := g.ExecuteDetailed(ctx, input)
+			if err != nil {
+				return "", oneapi.Usage{}, err
+			}
+			return combineResults(g.Agents(), rounds), roundsUsage(rounds), nil
+		},
+	})
+}
+
+// RegisterGraph 以指定模型名称注册依赖图，请求执行期间不使用依赖图自身的输出回调
+func (s *Server) RegisterGraph(name string, d *agent.DependencyGraph) error {
+	return s.register(&model{
+		name:   name,
+		kind:   KindGraph,
+		agents: d.Agents,
+		execute: func(ctx context.Context, input string) (string, oneapi.Usage, error) {
+			rounds, err := d.ExecuteDetailed(ctx, input)
+			if err != nil {
+				return "", oneapi.Usage{}, err
+			}
+			return combineResults(d.Agents(), rounds), roundsUsage(rounds), nil
+		},
+	})
+}
+
+func (s *Server) register(m *model) error {
+	if m.name == "" {
+		return fmt.Errorf("%w: model name is empty", agent.ErrInvalidParameters)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.models[m.name]; exists {
+		return fmt.Errorf("%w: %s", ErrModelExists, m.name)
+	}
+	m.created = time.Now().Unix()
+	s.models[m.name] = m
+	return nil
+}
+
+// Models 返回已注册的模型名称
+func (s *Server) Models() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	names := make([]string, 0, len(s.models))
+	for name := range s.models {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (s *Server) getModel(name string) (*model, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	m, ok := s.models[name]
+	return m, ok
+}
+
+// modelObject /v1/models 中的模型信息
+type modelObject struct {
+	ID      string `json:"id"`
+	Object  string `json:"object"`
+	Created int64  `json:"created"`
+	OwnedBy string `json:"owned_by"`
+	Kind    string `json:"kind"` // agent、group 或 graph
+}
+
+func (m *model) object() modelObject {
+	return modelObject{
+		ID:      m.name,
+		Object:  "model",
+		Created: m.created,
+		OwnedBy: "ambergen",
+		Kind:    m.kind,
+	}
+}
+
+func (s *Server) handleModels(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "invalid_request_error", "method not allowed")
+		return
+	}
+	list := make([]modelObject, 0)
+	for _, name := range s.Models() {
+		if m, ok := s.getModel(name); ok {
+			list = append(list, m.object())
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"object": "list",
+		"data":   list,
+	})
+}
+
+func (s *Server) handleModel(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "invalid_request_error", "method not allowed")
+		return
+	}
+	name := strings.TrimPrefix(r.URL.Path, "/v1/models/")
+	m, ok := s.getModel(name)
+	if !ok {
+		writeError(w, http.StatusNotFound, "model_not_found", fmt.Sprintf("model %s does not exist", name))
+		return
+	}
+	writeJSON(w, http.StatusOK, m.object())
+}
+
+// combineResults 将最后一轮中各Agent的回答合并为一条回复
+func combineResults(agents []agent.Agent, rounds []agent.RoundResult) string {
+	if len(rounds) == 0 {
+		return ""
+	}
+	last := rounds[len(rounds)-1]
+	var parts []string
+	for _, a := range agents {
+		result, ok := last[a.Name()]
+		if !ok || result.Status != agent.StatusSuccess || result.Content == "" {
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("[%s]: %s", a.Name(), result.Content))
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// roundsUsage 汇总所有轮次的Token用量
+func roundsUsage(rounds []agent.RoundResult) oneapi.Usage {
+	var usage oneapi.Usage
+	for _, results := range rounds {
+		for _, result := range results {
+			usage.TotalTokens += result.Tokens
+		}
+	}
+	return usage
+}
+
+// errorResponse OpenAI格式的错误
+type errorResponse struct {
+	Error struct {
+		Message string `json:"message"`
+		Type    string `json:"type"`
+	} `json:"error"`
+}
+
+func newErrorResponse(errType, message string) errorResponse {
+	var resp errorResponse
+	resp.Error.Message = message
+	resp.Error.Type = errType
+	return resp
+}
+
+func writeError(w http.ResponseWriter, status int, errType, message string) {
+	writeJSON(w, status, newErrorResponse(errType, message))
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
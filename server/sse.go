@@ -0,0 +1,64 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// sseStream Server-Sent Events 输出
+type sseStream struct {
+	mu      sync.Mutex
+	w       http.ResponseWriter
+	flusher http.Flusher
+}
+
+func newSSEStream(w http.ResponseWriter) (*sseStream, error) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return nil, errors.New("streaming not supported")
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	return &sseStream{w: w, flusher: flusher}, nil
+}
+
+// event 写入一条 data 事件
+func (s *sseStream) event(v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	s.write(fmt.Sprintf("data: %s\n\n", data))
+}
+
+// namedEvent 写入带ID和事件名的事件，客户端可通过 Last-Event-ID 断点续传
+func (s *sseStream) namedEvent(id int, name string, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	s.write(fmt.Sprintf("id: %d\nevent: %s\ndata: %s\n\n", id, name, data))
+}
+
+// keepAlive 写入注释行，防止空闲连接被代理断开
+func (s *sseStream) keepAlive() {
+	s.write(": keep-alive\n\n")
+}
+
+// done 写入结束标记
+func (s *sseStream) done() {
+	s.write("data: [DONE]\n\n")
+}
+
+func (s *sseStream) write(msg string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fmt.Fprint(s.w, msg)
+	s.flusher.Flush()
+}
@@ -6,6 +6,7 @@ import (
   "encoding/json"
   "fmt"
   "net/http"
+  "time"
 )
 
 // HTTPTool 实现HTTP调用的工具
@@ -29,6 +30,8 @@ func NewHTTPTool(name, url, method string, headers map[string]string) *HTTPTool
     Headers: headers,
     client:  &http.Client{},
   }
+  // 对端无响应时不应阻塞整个讨论
+  tool.SetLimits(Limits{Timeout: 30 * time.Second})
 
   // 添加基础参数规格
   tool.AddParameter("body", ParameterSpec{
@@ -0,0 +1,27 @@
+package tools
+
+import "time"
+
+// Limits 工具执行的资源限制，字段为0表示未设置，小于0表示不限制
+type Limits struct {
+	Timeout       time.Duration `json:"timeout,omitempty"`         // 单次执行的超时时间
+	MaxResultSize int           `json:"max_result_size,omitempty"` // 返回给模型的结果最大字节数，超出时截断
+}
+
+// LimitedTool 声明自身执行限制的工具，Agent可以覆盖这些限制
+type LimitedTool interface {
+	Tool
+	// GetLimits 获取工具的执行限制
+	GetLimits() Limits
+}
+
+// Merge 用o中已设置的字段覆盖l
+func (l Limits) Merge(o Limits) Limits {
+	if o.Timeout != 0 {
+		l.Timeout = o.Timeout
+	}
+	if o.MaxResultSize != 0 {
+		l.MaxResultSize = o.MaxResultSize
+	}
+	return l
+}
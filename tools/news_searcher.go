@@ -14,7 +14,6 @@ import (
 // NewsSearcher 新闻搜索工具
 type NewsSearcher struct {
 	*BaseTool
-	apiKey string
 	client *http.Client
 }
 
@@ -76,10 +75,9 @@ type NewsResponse struct {
 }
 
 func NewNewsSearcher() *NewsSearcher {
-	cfg, _ := config.LoadConfig("config.json")
+	// API密钥在执行时从配置读取，配置缺失时仍可创建工具（例如仅用于列出工具）
 	tool := &NewsSearcher{
 		BaseTool: NewBaseTool("news_searcher", "搜索最新新闻并提供摘要"),
-		apiKey:   cfg.WebSearchApiKey,
 		client:   &http.Client{Timeout: 10 * time.Second},
 	}
 
@@ -96,8 +94,10 @@ func (n *NewsSearcher) Execute(ctx context.Context, params map[string]interface{
 	if !ok || query == "" {
 		return nil, fmt.Errorf("invalid or missing query parameter")
 	}
-	fmt.Println("query: ", query)
-	cfg, _ := config.LoadConfig("config.json")
+	cfg, err := config.LoadConfig(config.Path)
+	if err != nil {
+		return nil, fmt.Errorf("load config failed: %w", err)
+	}
 	// 构建API URL
 	apiURL := cfg.WebSearchUrl
 	data := map[string]interface{}{
@@ -107,35 +107,33 @@ func (n *NewsSearcher) Execute(ctx context.Context, params map[string]interface{
 	// 将数据编码为JSON
 	jsonData, err := json.Marshal(data)
 	if err != nil {
-		fmt.Printf("JSON marshal failed: %v\n", err)
+		return nil, fmt.Errorf("marshal request failed: %w", err)
 	}
 
 	// 创建请求
 	req, err := http.NewRequestWithContext(ctx, "POST", apiURL, bytes.NewBuffer(jsonData))
 	if err != nil {
-		fmt.Printf("Create request failed: %v\n", err)
+		return nil, fmt.Errorf("create request failed: %w", err)
 	}
 
-	req.Header.Set("Authorization", "Bearer "+n.apiKey)
+	req.Header.Set("Authorization", "Bearer "+cfg.WebSearchApiKey)
 	req.Header.Set("Content-Type", "application/json")
 
 	// 发送请求
 	resp, err := n.client.Do(req)
 	if err != nil {
-		fmt.Printf("Request failed: %v\n", err)
+		return nil, fmt.Errorf("request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
 	// 解析响应
 	var newsResp NewsResponse
 	if err := json.NewDecoder(resp.Body).Decode(&newsResp); err != nil {
-		fmt.Printf("Decode response failed: %v\n", err)
+		return nil, fmt.Errorf("decode response failed: %w", err)
 	}
 
 	// 生成摘要
-	summary := n.generateSummary(newsResp)
-	fmt.Println("summary == ", summary)
-	return summary, nil
+	return n.generateSummary(newsResp), nil
 }
 
 func (n *NewsSearcher) generateSummary(articles NewsResponse) string {
@@ -30,6 +30,7 @@ type BaseTool struct {
 	name        string
 	description string
 	parameters  map[string]ParameterSpec
+	limits      Limits
 }
 
 func NewBaseTool(name, description string) *BaseTool {
@@ -55,6 +56,16 @@ func (b *BaseTool) GetParameters() map[string]ParameterSpec {
 	return b.parameters
 }
 
+// GetLimits 获取工具的执行限制
+func (b *BaseTool) GetLimits() Limits {
+	return b.limits
+}
+
+// SetLimits 设置工具的执行限制
+func (b *BaseTool) SetLimits(limits Limits) {
+	b.limits = limits
+}
+
 // AddParameter 添加参数定义
 func (b *BaseTool) AddParameter(name string, spec ParameterSpec) {
 	b.parameters[name] = spec
@@ -0,0 +1,314 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"multi-agent/agent"
+	"multi-agent/tools"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Registry 工作流可以引用的工具、输出回调、审批者和人类输入来源
+type Registry struct {
+	Tools      *tools.ToolRegistry
+	Callbacks  map[string]agent.OutputCallback
+	Approver   agent.Approver   // 审批 approve_tools 中的工具调用
+	HumanInput agent.HumanInput // 人类参与者（human: true）的输入来源
+}
+
+// NewRegistry 创建空的注册表
+func NewRegistry() *Registry {
+	return &Registry{
+		Tools:     tools.NewToolRegistry(),
+		Callbacks: make(map[string]agent.OutputCallback),
+	}
+}
+
+// RegisterTool 注册工具
+func (r *Registry) RegisterTool(tool tools.Tool) {
+	r.Tools.Register(tool.GetName(), tool)
+}
+
+// RegisterCallback 注册输出回调
+func (r *Registry) RegisterCallback(name string, callback agent.OutputCallback) {
+	r.Callbacks[name] = callback
+}
+
+// SetApprover 设置审批者，定义了 approve_tools 的工作流需要审批者才能构建
+func (r *Registry) SetApprover(approver agent.Approver) {
+	r.Approver = approver
+}
+
+// SetHumanInput 设置人类参与者的输入来源，定义了人类参与者的工作流需要它才能构建
+func (r *Registry) SetHumanInput(input agent.HumanInput) {
+	r.HumanInput = input
+}
+
+// Workflow 由工作流定义构建出的可执行对象
+type Workflow struct {
+	Spec   *Spec
+	Agents []agent.Agent
+	Group  *agent.Group
+	Graph  *agent.DependencyGraph
+}
+
+// Validate 校验工作流引用的工具和回调是否已在注册表中
+func (s *Spec) Validate(reg *Registry) error {
+	if reg == nil {
+		reg = NewRegistry()
+	}
+	c := &errorCollector{file: s.file}
+	for _, a := range s.Agents {
+		for i, name := range a.Tools {
+			if _, ok := reg.Tools.Get(name); !ok {
+				c.add(item(a.node, "tools", i), "Agent %s 引用了未注册的工具: %s", a.Name, name)
+			}
+		}
+	}
+	if s.Callback != "" {
+		if _, ok := reg.Callbacks[s.Callback]; !ok {
+			c.add(field(s.node, "callback"), "未注册的回调: %s", s.Callback)
+		}
+	}
+	return c.err()
+}
+
+// Build 根据工作流定义创建Agent以及Group或依赖图
+func Build(spec *Spec, reg *Registry) (*Workflow, error) {
+	if reg == nil {
+		reg = NewRegistry()
+	}
+	if err := spec.Validate(reg); err != nil {
+		return nil, err
+	}
+
+	for _, as := range spec.Agents {
+		if len(as.ApproveTools) > 0 && reg.Approver == nil {
+			// 敏感工具不能在没有人批准的情况下执行
+			return nil, fmt.Errorf("agent %s requires approval for tools %s but no approver is registered",
+				as.Name, strings.Join(as.ApproveTools, ", "))
+		}
+		if as.Human && reg.HumanInput == nil {
+			return nil, fmt.Errorf("agent %s is a human participant but no human input is registered", as.Name)
+		}
+	}
+
+	w := &Workflow{Spec: spec}
+	byName := make(map[string]agent.Agent)
+	for _, as := range spec.Agents {
+		var a agent.Agent
+		if as.Human {
+			a = newHumanAgent(as, reg)
+		} else {
+			a = newExpertAgent(as, reg)
+		}
+		w.Agents = append(w.Agents, a)
+		byName[as.Name] = a
+	}
+
+	callback := reg.Callbacks[spec.Callback]
+	policy, retries := spec.failurePolicy()
+
+	if spec.Group != nil {
+		group := agent.NewGroup(spec.Group.Rounds, spec.Group.Parallel, callback)
+		for _, a := range members(w.Agents, byName, spec.Group.Agents) {
+			group.AddAgent(a)
+		}
+		if spec.Group.Selector {
+			group.AddSelector(agent.NewDefaultSelector())
+		}
+		group.SetFailurePolicy(policy, retries)
+		w.Group = group
+		return w, nil
+	}
+
+	graph := agent.NewDependencyGraph(spec.Graph.Rounds, callback)
+	for _, a := range members(w.Agents, byName, spec.Graph.Agents) {
+		graph.AddAgent(a)
+	}
+	for _, e := range spec.Graph.Edges {
+		var err error
+		if e.When != nil {
+			err = graph.AddConditionalDependency(e.To, e.From, e.Label, e.When.condition(e.From))
+		} else {
+			err = graph.AddDependency(e.To, e.From)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("add edge %s -> %s failed: %w", e.From, e.To, err)
+		}
+	}
+	for _, l := range spec.Graph.Loops {
+		var until agent.EdgeCondition
+		if l.Until != nil {
+			until = l.Until.condition(l.From)
+		}
+		if err := graph.AddLoop(l.From, l.To, l.Label, l.MaxIterations, until); err != nil {
+			return nil, fmt.Errorf("add loop %s -> %s failed: %w", l.From, l.To, err)
+		}
+	}
+	for _, r := range spec.Graph.Routers {
+		if err := graph.SetRouter(r.Agent, r.route()); err != nil {
+			return nil, fmt.Errorf("set router %s failed: %w", r.Agent, err)
+		}
+	}
+	graph.SetFailurePolicy(policy, retries)
+	w.Graph = graph
+	return w, nil
+}
+
+// Execute 执行工作流
+func (w *Workflow) Execute(ctx context.Context, input string) ([]agent.RoundResult, error) {
+	if w.Group != nil {
+		return w.Group.ExecuteDetailed(ctx, input)
+	}
+	return w.Graph.ExecuteDetailed(ctx, input)
+}
+
+// ToMermaid 导出工作流拓扑为 Mermaid
+func (w *Workflow) ToMermaid(rounds []agent.RoundResult) string {
+	if w.Group != nil {
+		return w.Group.ToMermaid(rounds)
+	}
+	return w.Graph.ToMermaid(rounds)
+}
+
+// ToDOT 导出工作流拓扑为 Graphviz DOT
+func (w *Workflow) ToDOT(rounds []agent.RoundResult) string {
+	if w.Group != nil {
+		return w.Group.ToDOT(rounds)
+	}
+	return w.Graph.ToDOT(rounds)
+}
+
+// newHumanAgent 根据定义创建人类参与者
+func newHumanAgent(as *AgentSpec, reg *Registry) *agent.HumanAgent {
+	a := agent.NewHumanAgent(as.Name, as.Expertise, as.Description, reg.HumanInput)
+	if as.HumanTimeout != "" {
+		timeout, _ := time.ParseDuration(as.HumanTimeout)
+		a.SetHumanTimeout(timeout)
+	}
+	return a
+}
+
+// newExpertAgent 根据定义创建专家Agent并添加工具
+func newExpertAgent(as *AgentSpec, reg *Registry) *agent.ExpertAgent {
+	var a *agent.ExpertAgent
+	switch {
+	case as.Selector && as.Model != "":
+		a = agent.NewSelectorModelAgent(as.Name, as.Expertise, as.Description, as.Model)
+	case as.Selector:
+		a = agent.NewSelectorAgent(as.Name, as.Expertise, as.Description)
+	case as.Model != "":
+		a = agent.NewModelAgent(as.Name, as.Expertise, as.Description, as.Model)
+	default:
+		a = agent.NewAgent(as.Name, as.Expertise, as.Description)
+	}
+	a.SetStreamOutput(as.Stream)
+	a.SetToolLimits(as.MaxToolRounds, as.MaxRepeatedCalls, as.toolLimitAction())
+	a.SetMaxToolFailures(as.MaxToolFailures)
+	a.SetToolConcurrency(as.ToolConcurrency)
+	a.SetToolResourceLimits("", as.toolResourceLimits())
+	if len(as.ApproveTools) > 0 {
+		a.SetToolPolicy(agent.ToolPolicy{Approver: reg.Approver, RequireApproval: as.ApproveTools})
+	}
+	for _, name := range as.Tools {
+		tool, _ := reg.Tools.Get(name)
+		a.AddTool(tool)
+	}
+	return a
+}
+
+// members 返回参与执行的Agent，names为空时返回全部
+func members(all []agent.Agent, byName map[string]agent.Agent, names []string) []agent.Agent {
+	if len(names) == 0 {
+		return all
+	}
+	selected := make([]agent.Agent, 0, len(names))
+	for _, name := range names {
+		selected = append(selected, byName[name])
+	}
+	return selected
+}
+
+// failurePolicy 转换失败策略
+func (s *Spec) failurePolicy() (agent.FailurePolicy, int) {
+	if s.OnFailure == nil {
+		return agent.FailFast, 0
+	}
+	switch s.OnFailure.Policy {
+	case PolicyContinue:
+		return agent.ContinueOnError, 0
+	case PolicyRetry:
+		return agent.RetryThenContinue, s.OnFailure.Retries
+	default:
+		return agent.FailFast, 0
+	}
+}
+
+// toolLimitAction 转换工具调用超出限制时的处理方式
+func (as *AgentSpec) toolLimitAction() agent.ToolLimitAction {
+	if as.OnToolLimit == ToolLimitError {
+		return agent.FailOnToolLimit
+	}
+	return agent.ForceFinalAnswer
+}
+
+// toolResourceLimits 转换覆盖所有工具的执行限制，时长已在校验时检查
+func (as *AgentSpec) toolResourceLimits() tools.Limits {
+	timeout, _ := time.ParseDuration(as.ToolTimeout)
+	return tools.Limits{Timeout: timeout, MaxResultSize: as.MaxToolResult}
+}
+
+// condition 转换为边条件，未指定Agent时使用边的上游
+func (cs *ConditionSpec) condition(from string) agent.EdgeCondition {
+	name := cs.Agent
+	if name == "" {
+		name = from
+	}
+	return agent.OutputContains(name, cs.Contains...)
+}
+
+// route 转换为路由函数：选择所有关键词命中的下游，未命中时使用默认分支
+func (r *RouterSpec) route() agent.RouteFunc {
+	targets := make([]string, 0, len(r.Routes))
+	for target := range r.Routes {
+		targets = append(targets, target)
+	}
+	sort.Strings(targets)
+
+	return func(output string) []string {
+		output = strings.ToLower(output)
+		var selected []string
+		for _, target := range targets {
+			for _, keyword := range r.Routes[target] {
+				if strings.Contains(output, strings.ToLower(keyword)) {
+					selected = append(selected, target)
+					break
+				}
+			}
+		}
+		if len(selected) == 0 {
+			return r.Default
+		}
+		return selected
+	}
+}
+
+// RunID 返回最近一次执行的运行ID
+func (w *Workflow) RunID() string {
+	if w.Group != nil {
+		return w.Group.RunID()
+	}
+	return w.Graph.RunID()
+}
+
+// SetCallback 替换工作流的输出回调
+func (w *Workflow) SetCallback(callback agent.OutputCallback) {
+	if w.Group != nil {
+		w.Group.SetCallback(callback)
+		return
+	}
+	w.Graph.SetCallback(callback)
+}
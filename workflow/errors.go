@@ -0,0 +1,97 @@
+package workflow
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Error 工作流文件中的错误，带有行列号
+type Error struct {
+	File   string
+	Line   int
+	Column int
+	Msg    string
+}
+
+func (e *Error) Error() string {
+	var pos strings.Builder
+	if e.File != "" {
+		pos.WriteString(e.File)
+		pos.WriteString(":")
+	}
+	if e.Line > 0 {
+		pos.WriteString(strconv.Itoa(e.Line))
+		pos.WriteString(":")
+		if e.Column > 0 {
+			pos.WriteString(strconv.Itoa(e.Column))
+			pos.WriteString(":")
+		}
+	}
+	if pos.Len() == 0 {
+		return e.Msg
+	}
+	return pos.String() + " " + e.Msg
+}
+
+// ErrorList 校验时收集到的全部错误
+type ErrorList []*Error
+
+func (l ErrorList) Error() string {
+	msgs := make([]string, len(l))
+	for i, err := range l {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// errorCollector 收集校验错误
+type errorCollector struct {
+	file   string
+	errors ErrorList
+}
+
+// add 在指定节点位置记录错误，node为空时不带行号
+func (c *errorCollector) add(node *yaml.Node, format string, args ...interface{}) {
+	err := &Error{File: c.file, Msg: fmt.Sprintf(format, args...)}
+	if node != nil {
+		err.Line = node.Line
+		err.Column = node.Column
+	}
+	c.errors = append(c.errors, err)
+}
+
+// err 返回收集到的错误，没有错误时返回nil
+func (c *errorCollector) err() error {
+	if len(c.errors) == 0 {
+		return nil
+	}
+	return c.errors
+}
+
+var yamlLinePattern = regexp.MustCompile(`line (\d+): (.*)`)
+
+// wrapYAMLError 将yaml库的错误转换为带行号的错误列表
+func wrapYAMLError(file string, err error) ErrorList {
+	var typeErr *yaml.TypeError
+	if errors.As(err, &typeErr) {
+		var list ErrorList
+		for _, msg := range typeErr.Errors {
+			list = append(list, parseYAMLMessage(file, msg))
+		}
+		return list
+	}
+	return ErrorList{parseYAMLMessage(file, strings.TrimPrefix(err.Error(), "yaml: "))}
+}
+
+func parseYAMLMessage(file, msg string) *Error {
+	if m := yamlLinePattern.FindStringSubmatch(msg); m != nil {
+		line, _ := strconv.Atoi(m[1])
+		return &Error{File: file, Line: line, Msg: m[2]}
+	}
+	return &Error{File: file, Msg: msg}
+}
@@ -0,0 +1,369 @@
+package workflow
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// CurrentVersion 当前支持的工作流格式版本
+const CurrentVersion = 1
+
+// 失败策略名称
+const (
+	PolicyFailFast = "fail_fast"
+	PolicyContinue = "continue"
+	PolicyRetry    = "retry"
+)
+
+// 工具调用超出限制时的处理方式名称
+const (
+	ToolLimitAnswer = "answer"
+	ToolLimitError  = "error"
+)
+
+// Parse 解析YAML或JSON格式的工作流定义，并校验结构和Agent引用
+func Parse(data []byte, file string) (*Spec, error) {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, ErrorList{{File: file, Msg: "工作流文件为空"}}
+	}
+
+	var spec Spec
+	c := &errorCollector{file: file}
+	if err := yaml.Unmarshal(data, &spec); err != nil {
+		// 类型错误时yaml仍会解码其余字段，继续校验以便一次报告所有问题
+		var typeErr *yaml.TypeError
+		if !errors.As(err, &typeErr) || spec.node == nil {
+			return nil, wrapYAMLError(file, err)
+		}
+		c.errors = append(c.errors, wrapYAMLError(file, err)...)
+	}
+	if spec.node == nil || spec.node.Kind != yaml.MappingNode {
+		return nil, ErrorList{{File: file, Line: 1, Msg: "工作流定义必须是一个对象"}}
+	}
+
+	spec.file = file
+	spec.validate(c)
+	if err := c.err(); err != nil {
+		return nil, err
+	}
+	return &spec, nil
+}
+
+// ParseFile 读取并解析工作流文件
+func ParseFile(path string) (*Spec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read workflow failed: %w", err)
+	}
+	return Parse(data, path)
+}
+
+// Load 读取工作流文件并构建可执行的工作流
+func Load(path string, reg *Registry) (*Workflow, error) {
+	spec, err := ParseFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Build(spec, reg)
+}
+
+// validate 校验工作流结构
+func (s *Spec) validate(c *errorCollector) {
+	checkKeys(c, s.node, s)
+
+	if s.Version != 0 && s.Version != CurrentVersion {
+		c.add(field(s.node, "version"), "不支持的版本 %d，当前版本为 %d", s.Version, CurrentVersion)
+	}
+
+	// Agent定义
+	agents := make(map[string]bool)
+	// Agent定义中有类型错误时整个列表不会被解码，已报告过类型错误，不再报告为空
+	if seq := field(s.node, "agents"); len(s.Agents) == 0 && (seq.Kind != yaml.SequenceNode || len(seq.Content) == 0) {
+		c.add(seq, "至少需要定义一个Agent")
+	}
+	for i, a := range s.Agents {
+		if a == nil {
+			c.add(item(s.node, "agents", i), "agents[%d] 不能为空", i)
+			continue
+		}
+		checkKeys(c, a.node, a)
+		if a.Name == "" {
+			c.add(a.node, "agents[%d] 缺少 name", i)
+		} else if agents[a.Name] {
+			c.add(field(a.node, "name"), "Agent名称重复: %s", a.Name)
+		}
+		if a.Expertise == "" {
+			c.add(a.node, "Agent %s 缺少 expertise", a.Name)
+		}
+		if a.MaxToolRounds < 0 {
+			c.add(field(a.node, "max_tool_rounds"), "max_tool_rounds 不能为负数")
+		}
+		if a.MaxRepeatedCalls < 0 {
+			c.add(field(a.node, "max_repeated_calls"), "max_repeated_calls 不能为负数")
+		}
+		if a.MaxToolFailures < 0 {
+			c.add(field(a.node, "max_tool_failures"), "max_tool_failures 不能为负数")
+		}
+		if a.ToolConcurrency < 0 {
+			c.add(field(a.node, "tool_concurrency"), "tool_concurrency 不能为负数")
+		}
+		if a.ToolTimeout != "" {
+			if d, err := time.ParseDuration(a.ToolTimeout); err != nil || d <= 0 {
+				c.add(field(a.node, "tool_timeout"), "无效的 tool_timeout %q，应为正的时长，如 30s", a.ToolTimeout)
+			}
+		}
+		if a.MaxToolResult < 0 {
+			c.add(field(a.node, "max_tool_result"), "max_tool_result 不能为负数")
+		}
+		if a.Human {
+			if a.Selector {
+				c.add(field(a.node, "selector"), "人类参与者 %s 不能是选择型Agent", a.Name)
+			}
+			if len(a.Tools) > 0 {
+				c.add(field(a.node, "tools"), "人类参与者 %s 不能使用工具", a.Name)
+			}
+		}
+		if a.HumanTimeout != "" {
+			if d, err := time.ParseDuration(a.HumanTimeout); err != nil || d <= 0 {
+				c.add(field(a.node, "human_timeout"), "无效的 human_timeout %q，应为正的时长，如 5m", a.HumanTimeout)
+			} else if !a.Human {
+				c.add(field(a.node, "human_timeout"), "human_timeout 只能用于人类参与者（human: true）")
+			}
+		}
+		for j, name := range a.ApproveTools {
+			if name != "*" && !containsString(a.Tools, name) {
+				c.add(item(a.node, "approve_tools", j), "Agent %s 的 approve_tools 引用了未使用的工具: %s", a.Name, name)
+			}
+		}
+		switch a.OnToolLimit {
+		case "", ToolLimitAnswer, ToolLimitError:
+		default:
+			c.add(field(a.node, "on_tool_limit"), "未知的工具调用限制处理方式 %q，可选值: %s",
+				a.OnToolLimit, strings.Join([]string{ToolLimitAnswer, ToolLimitError}, ", "))
+		}
+		agents[a.Name] = true
+	}
+
+	// 执行方式
+	switch {
+	case s.Group == nil && s.Graph == nil:
+		c.add(s.node, "需要定义 group 或 graph 之一")
+	case s.Group != nil && s.Graph != nil:
+		c.add(field(s.node, "graph"), "group 和 graph 只能定义一个")
+	case s.Group != nil:
+		s.Group.validate(c, agents)
+	default:
+		s.Graph.validate(c, agents)
+	}
+
+	if s.OnFailure != nil {
+		checkKeys(c, s.OnFailure.node, s.OnFailure)
+		switch s.OnFailure.Policy {
+		case "", PolicyFailFast, PolicyContinue, PolicyRetry:
+		default:
+			c.add(field(s.OnFailure.node, "policy"), "未知的失败策略 %q，可选值: %s",
+				s.OnFailure.Policy, strings.Join([]string{PolicyFailFast, PolicyContinue, PolicyRetry}, ", "))
+		}
+		if s.OnFailure.Retries < 0 {
+			c.add(field(s.OnFailure.node, "retries"), "retries 不能为负数")
+		}
+	}
+}
+
+func (g *GroupSpec) validate(c *errorCollector, agents map[string]bool) {
+	checkKeys(c, g.node, g)
+	if g.Rounds < 0 {
+		c.add(field(g.node, "rounds"), "rounds 不能为负数")
+	}
+	checkAgentRefs(c, g.node, g.Agents, agents)
+}
+
+func (g *GraphSpec) validate(c *errorCollector, agents map[string]bool) {
+	checkKeys(c, g.node, g)
+	if g.Rounds < 0 {
+		c.add(field(g.node, "rounds"), "rounds 不能为负数")
+	}
+	checkAgentRefs(c, g.node, g.Agents, agents)
+
+	// 图中实际参与的Agent
+	members := agents
+	if len(g.Agents) > 0 {
+		members = make(map[string]bool)
+		for _, name := range g.Agents {
+			members[name] = true
+		}
+	}
+	ref := func(node *yaml.Node, key, name string) bool {
+		switch {
+		case name == "":
+			c.add(node, "缺少 %s", key)
+		case !members[name]:
+			c.add(field(node, key), "未定义的Agent: %s", name)
+		default:
+			return true
+		}
+		return false
+	}
+
+	upstream := make(map[string][]string)
+	for i, e := range g.Edges {
+		if e == nil {
+			c.add(item(g.node, "edges", i), "edges[%d] 不能为空", i)
+			continue
+		}
+		checkKeys(c, e.node, e)
+		okFrom := ref(e.node, "from", e.From)
+		okTo := ref(e.node, "to", e.To)
+		if okFrom && okTo && e.From == e.To {
+			c.add(e.node, "边的 from 和 to 不能相同: %s", e.From)
+			continue
+		}
+		if e.When != nil {
+			e.When.validate(c, members)
+		}
+		if okFrom && okTo {
+			upstream[e.To] = append(upstream[e.To], e.From)
+		}
+	}
+	if cycle := findCycle(upstream); cycle != nil {
+		c.add(field(g.node, "edges"), "依赖存在环: %s，循环请使用 loops 定义", strings.Join(cycle, " -> "))
+		return
+	}
+
+	for i, l := range g.Loops {
+		if l == nil {
+			c.add(item(g.node, "loops", i), "loops[%d] 不能为空", i)
+			continue
+		}
+		checkKeys(c, l.node, l)
+		if ref(l.node, "from", l.From) && ref(l.node, "to", l.To) &&
+			l.From != l.To && !reachable(upstream, l.From, l.To) {
+			c.add(l.node, "循环目标 %s 必须是 %s 的上游", l.To, l.From)
+		}
+		if l.MaxIterations < 0 {
+			c.add(field(l.node, "max_iterations"), "max_iterations 不能为负数")
+		}
+		if l.Until != nil {
+			l.Until.validate(c, members)
+		}
+	}
+
+	for i, r := range g.Routers {
+		if r == nil {
+			c.add(item(g.node, "routers", i), "routers[%d] 不能为空", i)
+			continue
+		}
+		checkKeys(c, r.node, r)
+		if !ref(r.node, "agent", r.Agent) {
+			continue
+		}
+		if len(r.Routes) == 0 {
+			c.add(r.node, "路由 %s 缺少 routes", r.Agent)
+		}
+		targets := make([]string, 0, len(r.Routes)+len(r.Default))
+		for target := range r.Routes {
+			targets = append(targets, target)
+		}
+		targets = append(targets, r.Default...)
+		for _, target := range targets {
+			if !containsString(upstream[target], r.Agent) {
+				c.add(field(r.node, "routes"), "路由目标 %s 不是 %s 的直接下游", target, r.Agent)
+			}
+		}
+	}
+}
+
+func (cs *ConditionSpec) validate(c *errorCollector, members map[string]bool) {
+	checkKeys(c, cs.node, cs)
+	if cs.Agent != "" && !members[cs.Agent] {
+		c.add(field(cs.node, "agent"), "未定义的Agent: %s", cs.Agent)
+	}
+	if len(cs.Contains) == 0 {
+		c.add(cs.node, "条件缺少 contains")
+	}
+}
+
+// checkAgentRefs 检查Agent名称列表是否都已定义
+func checkAgentRefs(c *errorCollector, node *yaml.Node, names []string, agents map[string]bool) {
+	for i, name := range names {
+		if !agents[name] {
+			c.add(item(node, "agents", i), "未定义的Agent: %s", name)
+		}
+	}
+}
+
+// findCycle 查找依赖中的环，返回环上的节点
+func findCycle(upstream map[string][]string) []string {
+	const (
+		visiting = 1
+		done     = 2
+	)
+	state := make(map[string]int)
+	var path []string
+	var visit func(string) []string
+	visit = func(name string) []string {
+		state[name] = visiting
+		path = append(path, name)
+		for _, up := range upstream[name] {
+			switch state[up] {
+			case visiting:
+				for i, n := range path {
+					if n == up {
+						return append(append([]string(nil), path[i:]...), up)
+					}
+				}
+			case 0:
+				if cycle := visit(up); cycle != nil {
+					return cycle
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		state[name] = done
+		return nil
+	}
+	for name := range upstream {
+		if state[name] == 0 {
+			if cycle := visit(name); cycle != nil {
+				return cycle
+			}
+		}
+	}
+	return nil
+}
+
+// reachable 判断 target 是否是 name 的（间接）上游
+func reachable(upstream map[string][]string, name, target string) bool {
+	seen := make(map[string]bool)
+	var walk func(string) bool
+	walk = func(n string) bool {
+		for _, up := range upstream[n] {
+			if up == target {
+				return true
+			}
+			if !seen[up] {
+				seen[up] = true
+				if walk(up) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	return walk(name)
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,219 @@
+package workflow
+
+import (
+	"errors"
+	"multi-agent/agent"
+	"multi-agent/tools"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// wantError 期望的错误位置和消息片段
+type wantError struct {
+	line, column int
+	msg          string
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		data string
+		want []wantError
+	}{
+		{
+			name: "unknown agent",
+			file: "bad.yaml",
+			data: `version: 1
+name: bad
+agents:
+  - name: writer
+    expertise: writing
+graph:
+  edges:
+    - from: writer
+      to: ghost
+`,
+			want: []wantError{{9, 11, "未定义的Agent: ghost"}},
+		},
+		{
+			name: "missing field",
+			file: "bad.yaml",
+			data: `version: 1
+name: bad
+agents:
+  - name: writer
+    description: 负责撰写
+group:
+  rounds: 1
+`,
+			want: []wantError{{4, 5, "Agent writer 缺少 expertise"}},
+		},
+		{
+			name: "cycle",
+			file: "bad.yaml",
+			data: `version: 1
+name: bad
+agents:
+  - name: a
+    expertise: x
+  - name: b
+    expertise: y
+graph:
+  edges:
+    - from: a
+      to: b
+    - from: b
+      to: a
+`,
+			want: []wantError{{10, 5, "依赖存在环"}},
+		},
+		{
+			// yaml只报告类型错误的行号，其余字段仍会继续校验
+			name: "bad type",
+			file: "bad.yaml",
+			data: `version: 1
+name: bad
+agents:
+  - name: writer
+    expertise: writing
+    timeout: 1m
+group:
+  rounds: many
+`,
+			want: []wantError{{8, 0, "cannot unmarshal !!str `many` into int"}, {6, 5, `未知字段 "timeout"`}},
+		},
+		{
+			name: "unknown agent in json",
+			file: "bad.json",
+			data: `{
+  "version": 1,
+  "name": "bad",
+  "agents": [{"name": "writer", "expertise": "writing"}],
+  "group": {"rounds": 1, "agents": ["writer", "ghost"]}
+}
+`,
+			want: []wantError{{5, 47, "未定义的Agent: ghost"}},
+		},
+		{
+			name: "missing field in json",
+			file: "bad.json",
+			data: `{
+  "version": 1,
+  "name": "bad",
+  "agents": [
+    {"name": "writer", "expertise": "writing"},
+    {"expertise": "editing"}
+  ],
+  "group": {"rounds": 1}
+}
+`,
+			want: []wantError{{6, 5, "agents[1] 缺少 name"}},
+		},
+		{
+			name: "cycle in json",
+			file: "bad.json",
+			data: `{
+  "version": 1,
+  "name": "bad",
+  "agents": [{"name": "a", "expertise": "x"}, {"name": "b", "expertise": "y"}],
+  "graph": {
+    "edges": [
+      {"from": "a", "to": "b"},
+      {"from": "b", "to": "a"}
+    ]
+  }
+}
+`,
+			want: []wantError{{6, 14, "依赖存在环"}},
+		},
+		{
+			name: "bad type in json",
+			file: "bad.json",
+			data: `{
+  "version": 1,
+  "name": "bad",
+  "agents": [{"name": "writer", "expertise": "writing", "stream": 3}],
+  "group": {"rounds": 1}
+}
+`,
+			want: []wantError{{4, 0, "cannot unmarshal !!int `3` into bool"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.data), tt.file)
+			var list ErrorList
+			if !errors.As(err, &list) {
+				t.Fatalf("got %v, want ErrorList", err)
+			}
+			if len(list) != len(tt.want) {
+				t.Fatalf("got %d errors, want %d:\n%v", len(list), len(tt.want), err)
+			}
+			for i, want := range tt.want {
+				got := list[i]
+				if got.File != tt.file || got.Line != want.line || got.Column != want.column ||
+					!strings.Contains(got.Msg, want.msg) {
+					t.Errorf("error %d = %q, want %s:%d:%d %s", i, got.Error(), tt.file, want.line, want.column, want.msg)
+				}
+			}
+		})
+	}
+}
+
+// nopCallback 不输出任何内容的回调
+type nopCallback struct{}
+
+func (nopCallback) OnStart(agentName string)                             {}
+func (nopCallback) OnContent(agentName string, content string)           {}
+func (nopCallback) OnComplete(agentName string)                          {}
+func (nopCallback) OnRoundComplete(round int, results map[string]string) {}
+func (nopCallback) OnAllComplete(allResults []map[string]string)         {}
+
+func TestBuildExamples(t *testing.T) {
+	reg := NewRegistry()
+	reg.RegisterTool(tools.NewCalculator())
+	reg.RegisterCallback("default", nopCallback{})
+
+	for _, name := range []string{"review.yaml", "discussion.json"} {
+		t.Run(name, func(t *testing.T) {
+			w, err := Load(filepath.Join("..", "examples", "workflows", name), reg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(w.Agents) != len(w.Spec.Agents) {
+				t.Errorf("built %d agents, want %d", len(w.Agents), len(w.Spec.Agents))
+			}
+			if (w.Group == nil) == (w.Graph == nil) {
+				t.Error("workflow should build exactly one of group and graph")
+			}
+		})
+	}
+}
+
+func TestBuildRequiresApprover(t *testing.T) {
+	spec, err := Parse([]byte(`version: 1
+name: calc
+agents:
+  - name: writer
+    expertise: math
+    tools: [calculator]
+    approve_tools: [calculator]
+group:
+  rounds: 1
+`), "calc.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	reg := NewRegistry()
+	reg.RegisterTool(tools.NewCalculator())
+	if _, err := Build(spec, reg); err == nil {
+		t.Fatal("Build succeeded without an approver")
+	}
+	reg.SetApprover(agent.NewChannelApprover(0))
+	if _, err := Build(spec, reg); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,214 @@
+package workflow
+
+import (
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Spec 声明式工作流定义，支持YAML和JSON两种格式
+type Spec struct {
+	Version     int          `yaml:"version"`     // 格式版本，当前为1
+	Name        string       `yaml:"name"`        // 工作流名称
+	Description string       `yaml:"description"` // 工作流描述
+	Agents      []*AgentSpec `yaml:"agents"`      // Agent定义
+	Group       *GroupSpec   `yaml:"group"`       // 以Group方式执行，与graph二选一
+	Graph       *GraphSpec   `yaml:"graph"`       // 以依赖图方式执行，与group二选一
+	Callback    string       `yaml:"callback"`    // 输出回调名称
+	OnFailure   *FailureSpec `yaml:"on_failure"`  // 失败处理策略
+
+	node *yaml.Node
+	file string // 来源文件，用于错误信息
+}
+
+// AgentSpec Agent定义
+type AgentSpec struct {
+	Name        string   `yaml:"name"`        // 名称，必须唯一
+	Expertise   string   `yaml:"expertise"`   // 专业领域
+	Description string   `yaml:"description"` // 专家描述
+	Model       string   `yaml:"model"`       // 模型，为空时使用配置中的默认模型
+	Stream      bool     `yaml:"stream"`      // 是否流式输出
+	Selector    bool     `yaml:"selector"`    // 是否为选择型Agent
+	Tools       []string `yaml:"tools"`       // 工具名称列表
+
+	MaxToolRounds    int    `yaml:"max_tool_rounds"`    // 一次执行中最多的工具调用轮数，0 表示默认值
+	MaxRepeatedCalls int    `yaml:"max_repeated_calls"` // 相同参数的工具调用最多执行的次数，0 表示默认值
+	OnToolLimit      string `yaml:"on_tool_limit"`      // 超出限制时：answer（默认，要求直接回答）或 error
+	MaxToolFailures  int    `yaml:"max_tool_failures"`  // 连续失败多少次工具调用后终止执行，0 表示默认值
+	ToolConcurrency  int    `yaml:"tool_concurrency"`   // 同时执行的工具调用数，1 表示依次执行，0 表示默认值
+	ToolTimeout      string `yaml:"tool_timeout"`       // 覆盖所有工具的执行超时，如 30s，为空时使用工具自身的设置
+	MaxToolResult    int    `yaml:"max_tool_result"`    // 覆盖所有工具返回给模型的结果最大字节数，0 表示使用工具自身的设置
+
+	ApproveTools []string `yaml:"approve_tools"` // 需要人工批准才能执行的工具，"*" 表示所有工具，需要在注册表中设置 Approver
+
+	Human        bool   `yaml:"human"`         // 是否为人类参与者，需要在注册表中设置 HumanInput
+	HumanTimeout string `yaml:"human_timeout"` // 等待人类回复的时间，如 5m，为空时使用默认值
+
+	node *yaml.Node
+}
+
+// GroupSpec Group执行配置
+type GroupSpec struct {
+	Rounds   int      `yaml:"rounds"`   // 最大轮数
+	Parallel bool     `yaml:"parallel"` // 是否并行执行
+	Selector bool     `yaml:"selector"` // 是否使用默认选择器
+	Agents   []string `yaml:"agents"`   // 参与的Agent，为空时使用全部Agent
+
+	node *yaml.Node
+}
+
+// GraphSpec 依赖图执行配置
+type GraphSpec struct {
+	Rounds  int           `yaml:"rounds"`  // 最大轮数
+	Agents  []string      `yaml:"agents"`  // 参与的Agent，为空时使用全部Agent
+	Edges   []*EdgeSpec   `yaml:"edges"`   // 依赖边
+	Loops   []*LoopSpec   `yaml:"loops"`   // 循环边
+	Routers []*RouterSpec `yaml:"routers"` // 路由
+
+	node *yaml.Node
+}
+
+// EdgeSpec 依赖边：from 执行完成后执行 to
+type EdgeSpec struct {
+	From  string         `yaml:"from"`
+	To    string         `yaml:"to"`
+	Label string         `yaml:"label"` // 条件说明
+	When  *ConditionSpec `yaml:"when"`  // 条件，为空表示无条件依赖
+
+	node *yaml.Node
+}
+
+// LoopSpec 循环边：from 执行完成后若 until 不成立则回到 to
+type LoopSpec struct {
+	From          string         `yaml:"from"`
+	To            string         `yaml:"to"`
+	Label         string         `yaml:"label"`
+	MaxIterations int            `yaml:"max_iterations"`
+	Until         *ConditionSpec `yaml:"until"`
+
+	node *yaml.Node
+}
+
+// ConditionSpec 输出条件：指定Agent的输出包含任一关键词时成立
+type ConditionSpec struct {
+	Agent    string   `yaml:"agent"` // 为空时使用边的 from
+	Contains []string `yaml:"contains"`
+
+	node *yaml.Node
+}
+
+// RouterSpec 路由：根据Agent输出中的关键词选择下游分支
+type RouterSpec struct {
+	Agent   string              `yaml:"agent"`
+	Routes  map[string][]string `yaml:"routes"`  // 下游Agent -> 关键词
+	Default []string            `yaml:"default"` // 没有关键词命中时选择的下游Agent
+
+	node *yaml.Node
+}
+
+// FailureSpec 失败处理策略
+type FailureSpec struct {
+	Policy  string `yaml:"policy"`  // fail_fast、continue 或 retry
+	Retries int    `yaml:"retries"` // retry 策略下的重试次数
+
+	node *yaml.Node
+}
+
+// 以下 UnmarshalYAML 在解码时记录节点位置，用于输出带行号的校验错误
+
+func (s *Spec) UnmarshalYAML(node *yaml.Node) error {
+	type plain Spec
+	s.node = node
+	return node.Decode((*plain)(s))
+}
+
+func (a *AgentSpec) UnmarshalYAML(node *yaml.Node) error {
+	type plain AgentSpec
+	a.node = node
+	return node.Decode((*plain)(a))
+}
+
+func (g *GroupSpec) UnmarshalYAML(node *yaml.Node) error {
+	type plain GroupSpec
+	g.node = node
+	return node.Decode((*plain)(g))
+}
+
+func (g *GraphSpec) UnmarshalYAML(node *yaml.Node) error {
+	type plain GraphSpec
+	g.node = node
+	return node.Decode((*plain)(g))
+}
+
+func (e *EdgeSpec) UnmarshalYAML(node *yaml.Node) error {
+	type plain EdgeSpec
+	e.node = node
+	return node.Decode((*plain)(e))
+}
+
+func (l *LoopSpec) UnmarshalYAML(node *yaml.Node) error {
+	type plain LoopSpec
+	l.node = node
+	return node.Decode((*plain)(l))
+}
+
+func (c *ConditionSpec) UnmarshalYAML(node *yaml.Node) error {
+	type plain ConditionSpec
+	c.node = node
+	return node.Decode((*plain)(c))
+}
+
+func (r *RouterSpec) UnmarshalYAML(node *yaml.Node) error {
+	type plain RouterSpec
+	r.node = node
+	return node.Decode((*plain)(r))
+}
+
+func (f *FailureSpec) UnmarshalYAML(node *yaml.Node) error {
+	type plain FailureSpec
+	f.node = node
+	return node.Decode((*plain)(f))
+}
+
+// field 返回映射节点中指定字段的值节点，不存在时返回映射节点本身
+func field(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return node
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return node
+}
+
+// item 返回序列字段中第i个元素的节点，不存在时返回字段节点
+func item(node *yaml.Node, key string, i int) *yaml.Node {
+	seq := field(node, key)
+	if seq != nil && seq.Kind == yaml.SequenceNode && i < len(seq.Content) {
+		return seq.Content[i]
+	}
+	return seq
+}
+
+// checkKeys 检查映射节点中是否存在结构体未定义的字段
+func checkKeys(c *errorCollector, node *yaml.Node, v interface{}) {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return
+	}
+	allowed := make(map[string]bool)
+	t := reflect.TypeOf(v).Elem()
+	for i := 0; i < t.NumField(); i++ {
+		if tag := t.Field(i).Tag.Get("yaml"); tag != "" {
+			allowed[strings.Split(tag, ",")[0]] = true
+		}
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key := node.Content[i]
+		if !allowed[key.Value] {
+			c.add(key, "未知字段 %q", key.Value)
+		}
+	}
+}